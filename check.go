@@ -0,0 +1,50 @@
+package configstore
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// FieldResult is the outcome of loading a single field, as reported by Check
+type FieldResult struct {
+	Field  string
+	EnvVar string
+	// Value is the raw value the field would be parsed from, obscured with the mask if the field is secret
+	Value string
+	OK    bool
+	Err   error
+}
+
+// Check loads every field of the config from the execution environment and reports whether each one would load
+// cleanly, without changing the config. Unlike ValidateEnv it evaluates every field, never panics and doesn't call
+// Validate, so it suits a --check-config command that diagnoses the whole config at once
+func Check(c interface{}) []FieldResult {
+	mustBeConfigPointer(c)
+	l := newLoader(snapshotEnv())
+	structValue := reflect.New(reflect.ValueOf(c).Elem().Type()).Elem()
+	var results []FieldResult
+	for _, field := range configFields(structValue.Type()) {
+		envVar, _, _ := l.lookupEnv(field.Tag)
+		result := FieldResult{Field: field.Name, EnvVar: envVar}
+		if !isStructSlice(field.Type) {
+			result.Value, _, _ = l.getEnvValueSourced(field.Tag)
+			if isEnvValueSecret(field.Tag) {
+				result.Value = maskSecret(field.Tag, result.Value, getMask())
+			}
+		}
+		result.Err = l.checkField(field, structValue.FieldByIndex(field.Index))
+		result.OK = result.Err == nil
+		results = append(results, result)
+	}
+	return results
+}
+
+// checkField loads a single field, turning any panic into an error
+func (l *loader) checkField(field reflect.StructField, fieldValue reflect.Value) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("field %s could not be loaded: %v", field.Name, r)
+		}
+	}()
+	return l.loadField(field, fieldValue)
+}
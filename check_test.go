@@ -0,0 +1,35 @@
+package configstore
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type checkStruct struct {
+	Host     string     `env:"CHECK_HOST" default:"localhost"`
+	Port     int32      `env:"CHECK_PORT" default:"80"`
+	Password string     `env:"CHECK_PASSWORD" secret:"true" minlen:"8"`
+	Ratio    complex128 `env:"CHECK_RATIO"`
+}
+
+func TestCheck(t *testing.T) {
+	t.Setenv("CHECK_PORT", "http")
+	t.Setenv("CHECK_PASSWORD", "hunter2")
+	s := checkStruct{Host: "preset"}
+	results := Check(&s)
+	assert.Equal(t, checkStruct{Host: "preset"}, s)
+	assert.Len(t, results, 4)
+
+	assert.Equal(t, FieldResult{Field: "Host", EnvVar: "CHECK_HOST", Value: "localhost", OK: true}, results[0])
+
+	assert.Equal(t, "http", results[1].Value)
+	assert.False(t, results[1].OK)
+	assert.EqualError(t, results[1].Err, "value for CHECK_PORT could not be parsed as an integer")
+
+	assert.Equal(t, "********", results[2].Value)
+	assert.EqualError(t, results[2].Err, "value for CHECK_PASSWORD is too short, it must be at least 8 characters but is 7")
+
+	assert.False(t, results[3].OK)
+	var kindErr *UnsupportedKindError
+	assert.ErrorAs(t, results[3].Err, &kindErr)
+}
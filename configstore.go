@@ -1,28 +1,677 @@
 package configstore
 
 import (
+	"context"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"math"
+	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"text/tabwriter"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
-// LoadOnce config from the execution environment
+// Validator can be implemented by a config struct to enforce invariants across fields that can't be expressed with
+// struct tags. Validate is called once all fields have been loaded successfully
+type Validator interface {
+	Validate() error
+}
+
+// Defaulter can be implemented by a config struct to compute defaults that depend on other fields, such as a metrics
+// port that defaults to the main port plus one. ApplyDefaults is called once all fields have been loaded successfully,
+// before Validate
+type Defaulter interface {
+	ApplyDefaults()
+}
+
+// ValidationError is returned when the config struct's Validate method fails, which distinguishes it from errors
+// encountered while parsing individual fields
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("config validation failed: %v", e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// UnsupportedKindError is returned for a field whose kind can't be loaded, such as a channel or a function, unless it
+// has a registered parser, implements encoding.TextUnmarshaler or is decoded from JSON
+type UnsupportedKindError struct {
+	Field string
+	Kind  reflect.Kind
+}
+
+func (e *UnsupportedKindError) Error() string {
+	names := make([]string, len(supportedKinds))
+	for i, kind := range supportedKinds {
+		names[i] = kind.String()
+	}
+	return fmt.Sprintf("field %s has unsupported kind %s, fields must be one of %s, or have a parser", e.Field, e.Kind,
+		strings.Join(names, ", "))
+}
+
+// supportedKinds are the kinds fillField can load without a parser. Keep it in sync with the cases of fillField
+var supportedKinds = []reflect.Kind{
+	reflect.String, reflect.Bool,
+	reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+	reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+	reflect.Float32, reflect.Float64,
+	reflect.Slice, reflect.Array, reflect.Map, reflect.Interface,
+}
+
+// FieldErrors is returned when one or more fields can't be loaded. Every field is attempted before returning, so all
+// the problems with a config can be fixed in one go
+type FieldErrors struct {
+	Errs []error
+}
+
+func (e *FieldErrors) Error() string {
+	if len(e.Errs) == 1 {
+		return e.Errs[0].Error()
+	}
+	messages := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		messages[i] = "\n  - " + err.Error()
+	}
+	return fmt.Sprintf("%d config fields could not be loaded:%s", len(e.Errs), strings.Join(messages, ""))
+}
+
+func (e *FieldErrors) Unwrap() []error {
+	return e.Errs
+}
+
+// urlType is handled explicitly because url.URL doesn't implement encoding.TextUnmarshaler. net.IP does, so it needs
+// no special treatment
+var urlType = reflect.TypeOf(url.URL{})
+
+// durationType is handled explicitly so that durations are written as 5s rather than a number of nanoseconds
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// LoadOnce config from the execution environment. This is a thin wrapper around Unmarshal for programs that manage
+// their config as a singleton, and panics if the config can't be loaded. The package keeps no once-guard of its own, so
+// the config is only cached by the caller's sync.Once, and replacing it with a fresh one resets the singleton
 func LoadOnce(c interface{}, testMode bool, once *sync.Once) {
 	if testMode {
-		zap.L().Info("WARNING: running in test mode, configuration not loaded from env")
+		getLogger().Info("WARNING: running in test mode, configuration not loaded from env")
+	} else {
+		once.Do(func() {
+			if err := Unmarshal(c); err != nil {
+				panic(err.Error())
+			}
+		})
+	}
+}
+
+// LoadAndPrint is the same as LoadOnce, but also prints the config with Print once it has been loaded, so startup code
+// only needs one line. The config is only printed on the call that loads it, and not at all in test mode
+func LoadAndPrint(c interface{}, testMode bool, once *sync.Once) {
+	if testMode {
+		LoadOnce(c, testMode, once)
+		return
+	}
+	once.Do(func() {
+		if err := UnmarshalAndPrint(c); err != nil {
+			panic(err.Error())
+		}
+	})
+}
+
+// UnmarshalAndPrint is the same as Unmarshal, but prints the config with the options once it has loaded successfully
+func UnmarshalAndPrint(c interface{}, opts ...PrintOption) error {
+	if err := Unmarshal(c); err != nil {
+		return err
+	}
+	Print(c, opts...)
+	return nil
+}
+
+// Unmarshal fills the config from the execution environment, returning a *FieldErrors listing every value that can't
+// be parsed. If the config implements Validator its Validate method is called after all fields are loaded, and any
+// failure is returned as a *ValidationError. The config is loaded afresh on every call, so caching it is left to the
+// caller. The environment is read once at the start of every load, so concurrent calls to os.Setenv can't leave a
+// config with a mix of old and new values
+func Unmarshal(c interface{}) error {
+	return LoadWithLookup(c, snapshotEnv())
+}
+
+// Load is the same as Unmarshal
+func Load(c interface{}) error {
+	return Unmarshal(c)
+}
+
+// ValidateEnv checks that the config would load cleanly from the execution environment without changing it. The
+// environment is loaded into a fresh copy of the config, so every value is parsed and validated exactly as Load would,
+// and the same errors are returned. This lets deploy pipelines fail fast on bad settings
+func ValidateEnv(c interface{}) error {
+	if err := checkConfigPointer(c); err != nil {
+		return err
+	}
+	return Load(reflect.New(reflect.ValueOf(c).Elem().Type()).Interface())
+}
+
+// MissingRequired returns the primary env variables of the fields tagged 'required=true' that aren't set in the
+// execution environment and have no default, in declaration order, including those of every element of a slice of
+// structs that is set. The config isn't changed, so this suits pre-flight checks and setup tools that prompt for the
+// missing settings
+func MissingRequired(c interface{}) []string {
+	mustBeConfigPointer(c)
+	l := newLoader(snapshotEnv())
+	var missing []string
+	addMissing := func(field reflect.StructField) {
+		if !isTagTrue(field.Tag, "required") || hasDefault(field.Tag) {
+			return
+		}
+		if _, _, ok := l.lookupEnv(field.Tag); !ok {
+			missing = append(missing, envVarName(field.Tag))
+		}
+	}
+	structType := reflect.ValueOf(c).Elem().Type()
+	for _, field := range configFields(structType) {
+		if !isStructSlice(field.Type) {
+			addMissing(field)
+			continue
+		}
+		for i := 0; l.isElementSet(indexedFields(field, i)); i++ {
+			for _, elementField := range indexedFields(field, i) {
+				addMissing(elementField)
+			}
+		}
+	}
+	return missing
+}
+
+// LoadFromMap is the same as Unmarshal, but reads values from the map rather than the execution environment. This is useful
+// in tests, which can load configs in parallel without setting env variables, and for embedding the loader in a system
+// that already has its settings in a map
+func LoadFromMap(c interface{}, values map[string]string) error {
+	return LoadWithLookup(c, mapLookup(values))
+}
+
+// LoadDefaults fills every field of the config with its default, without consulting the environment, and runs the same
+// validation as Unmarshal. Unlike the test mode of LoadOnce, which leaves preset values untouched, every field is
+// overwritten. This is useful for documentation and for seeding tests
+func LoadDefaults(c interface{}) error {
+	return LoadFromMap(c, nil)
+}
+
+// LoadWithLookup is the same as Load, but reads values through the lookup function, which behaves like os.LookupEnv.
+// This allows configs to be loaded from any source of settings
+func LoadWithLookup(c interface{}, lookup func(key string) (string, bool)) error {
+	return newLoader(lookup).load(c)
+}
+
+// mapLookup returns a lookup function reading from the map
+func mapLookup(values map[string]string) func(key string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+// snapshotEnv returns a lookup function reading from a copy of the execution environment taken when it's called. Each
+// load takes its own snapshot, so it sees a consistent view of the environment, while later loads see any changes
+func snapshotEnv() func(key string) (string, bool) {
+	return mapLookup(environMap(os.Environ()))
+}
+
+// environMap converts a list of KEY=value entries in the form returned by os.Environ to a map
+func environMap(environ []string) map[string]string {
+	values := make(map[string]string, len(environ))
+	for _, entry := range environ {
+		if name, value, ok := strings.Cut(entry, "="); ok {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// LoadStrict is the same as Load, but additionally returns an error if any env variable starting with the prefix doesn't
+// correspond to a field in the config. This catches typos such as MYAPP_PROT that would otherwise silently leave a
+// setting at its default
+func LoadStrict(c interface{}, prefix string) error {
+	environ := os.Environ()
+	if err := LoadFromMap(c, environMap(environ)); err != nil {
+		return err
+	}
+	if unknown := unknownEnvVars(c, prefix, environ); len(unknown) > 0 {
+		return fmt.Errorf("unknown env variables with prefix %s: %s", prefix, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// LoadCaseInsensitive is the same as Load, but if an env variable isn't set under its exact name any variable whose name
+// only differs in case is used instead, so port=8080 satisfies env:"PORT". An exact match always takes precedence, and
+// if several other variants are set the first in sorted order is used, which puts upper case names before lower case
+func LoadCaseInsensitive(c interface{}) error {
+	return newLoader(caseInsensitiveLookup(os.Environ())).load(c)
+}
+
+// caseInsensitiveLookup returns a lookup function which falls back to a case insensitive match against the environ
+// entries when a variable isn't set under its exact name
+func caseInsensitiveLookup(environ []string) func(key string) (string, bool) {
+	exact := environMap(environ)
+	entries := append([]string(nil), environ...)
+	sort.Strings(entries)
+	folded := map[string]string{}
+	for _, entry := range entries {
+		name, value, _ := strings.Cut(entry, "=")
+		if _, ok := folded[strings.ToUpper(name)]; !ok {
+			folded[strings.ToUpper(name)] = value
+		}
+	}
+	return func(key string) (string, bool) {
+		if value, ok := exact[key]; ok {
+			return value, true
+		}
+		value, ok := folded[strings.ToUpper(key)]
+		return value, ok
+	}
+}
+
+// unknownEnvVars returns the sorted names of the variables in the environment that start with the prefix but aren't
+// read by any field in the config
+func unknownEnvVars(c interface{}, prefix string, environ []string) []string {
+	known := map[string]bool{}
+	var structSlices []reflect.StructField
+	addKnownEnvVars(reflect.ValueOf(c).Elem().Type(), known, &structSlices, map[reflect.Type]bool{})
+
+	var unknown []string
+	for _, entry := range environ {
+		name, _, _ := strings.Cut(entry, "=")
+		if strings.HasPrefix(name, prefix) && !known[name] && !isIndexedEnvVar(name, structSlices) {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// addKnownEnvVars records the env variables read by the fields of a config type, including those of every variant
+// registered for its interface fields, along with its slices of structs. Types already visited are skipped, so variants
+// that refer back to their parent don't recurse forever
+func addKnownEnvVars(structType reflect.Type, known map[string]bool, structSlices *[]reflect.StructField,
+	visited map[reflect.Type]bool) {
+	if visited[structType] {
+		return
+	}
+	visited[structType] = true
+	for _, field := range configFields(structType) {
+		for _, name := range envVarNames(field.Tag) {
+			known[name] = true
+		}
+		if isStructSlice(field.Type) {
+			*structSlices = append(*structSlices, field)
+		}
+		if field.Type.Kind() == reflect.Interface {
+			for _, factory := range getVariants(envVarName(field.Tag)) {
+				variantType := reflect.TypeOf(factory())
+				if variantType.Kind() == reflect.Ptr && variantType.Elem().Kind() == reflect.Struct {
+					addKnownEnvVars(variantType.Elem(), known, structSlices, visited)
+				}
+			}
+		}
+	}
+	// retired variables are already warned about, and failing on them would break deployments that haven't removed them
+	for _, field := range retiredFields(structType) {
+		for _, name := range envVarNames(field.Tag) {
+			known[name] = true
+		}
+	}
+}
+
+// Merge copies every non-zero config field from src over dst, which lets an overlay such as a per-tenant config only
+// specify the settings that differ from a base config. Both must be pointers to the same struct type. A pointer field
+// is copied whenever it is non-nil, even if it points to a zero value, so pointers can distinguish "unset" from "zero".
+// Slices and maps are shared rather than copied
+func Merge(dst, src interface{}) error {
+	if err := checkConfigPointer(dst); err != nil {
+		return err
+	}
+	if err := checkConfigPointer(src); err != nil {
+		return err
+	}
+	dstValue := reflect.ValueOf(dst).Elem()
+	srcValue := reflect.ValueOf(src).Elem()
+	if dstValue.Type() != srcValue.Type() {
+		return fmt.Errorf("cannot merge %s into %s", srcValue.Type(), dstValue.Type())
+	}
+	for _, field := range configFields(dstValue.Type()) {
+		if srcValue.FieldByIndex(field.Index).IsZero() {
+			continue
+		}
+		dstValue.FieldByIndex(field.Index).Set(srcValue.FieldByIndex(field.Index))
+	}
+	return nil
+}
+
+// Print will pretty print the contents of the configuration object alongside the declared defaults. Any struct values
+// with a 'secret=true' struct tag will be obscured if set, as will their defaults. By default the table is written to
+// stdout, which can be changed along with the format, ordering and mask using PrintOptions
+func Print(c interface{}, opts ...PrintOption) {
+	options := printOptions{writer: os.Stdout, format: FormatTable, mask: getMask()}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var sources map[string]Source
+	if options.report != nil {
+		sources = map[string]Source{}
+		for _, field := range options.report.Fields {
+			sources[field.Field] = field.Source
+		}
+	}
+
+	var rows []printRow
+	for _, row := range printRows(c, options.mask, options.sensitivePatterns) {
+		row.source = sources[row.name]
+		if (!options.overridesOnly || row.overridden) && (!options.hideEmpty || !row.empty) {
+			rows = append(rows, row)
+		}
+	}
+	if options.sorted {
+		sortRowsByEnvVar(rows)
+	}
+
+	switch options.format {
+	case FormatJSON:
+		writeJSON(options.writer, rows)
+	default:
+		writeTable(options.writer, rows, options.report != nil)
+	}
+}
+
+// Format is an output format for Print
+type Format int
+
+const (
+	// FormatTable is an aligned table for humans to read, which is the default
+	FormatTable Format = iota
+	// FormatJSON is a JSON array with an object for each setting
+	FormatJSON
+)
+
+// PrintOption changes the output of Print
+type PrintOption func(*printOptions)
+
+type printOptions struct {
+	writer        io.Writer
+	format        Format
+	sorted        bool
+	overridesOnly bool
+	hideEmpty     bool
+	mask          string
+	report        *Report
+	// sensitivePatterns are matched against the names of fields without a 'secret' tag to mask them anyway
+	sensitivePatterns []string
+}
+
+// WithWriter writes the output of Print to w instead of stdout
+func WithWriter(w io.Writer) PrintOption {
+	return func(options *printOptions) {
+		options.writer = w
+	}
+}
+
+// WithFormat changes the format of the output of Print
+func WithFormat(format Format) PrintOption {
+	return func(options *printOptions) {
+		options.format = format
+	}
+}
+
+// WithSort orders the output of Print alphabetically by env variable rather than by declaration order
+func WithSort(sorted bool) PrintOption {
+	return func(options *printOptions) {
+		options.sorted = sorted
+	}
+}
+
+// WithOverridesOnly limits the output of Print to settings whose value differs from their declared default
+func WithOverridesOnly(overridesOnly bool) PrintOption {
+	return func(options *printOptions) {
+		options.overridesOnly = overridesOnly
+	}
+}
+
+// WithHideEmpty omits settings whose value is the zero value of their type, such as an empty string, 0, false or an
+// empty slice or map, from the output of Print. Secrets that aren't set are omitted too
+func WithHideEmpty(hideEmpty bool) PrintOption {
+	return func(options *printOptions) {
+		options.hideEmpty = hideEmpty
+	}
+}
+
+// WithMask obscures secrets with the given mask rather than the one set with SetMask
+func WithMask(mask string) PrintOption {
+	return func(options *printOptions) {
+		options.mask = mask
+	}
+}
+
+// WithReport adds a SOURCE column to the output of Print, showing where each value came from according to the report
+// returned by LoadWithReport. Secrets are still masked, but their source is shown
+func WithReport(report Report) PrintOption {
+	return func(options *printOptions) {
+		options.report = &report
+	}
+}
+
+// defaultSensitivePatterns are the parts of names that WithRedactByName treats as sensitive by default
+var defaultSensitivePatterns = []string{"PASSWORD", "SECRET", "TOKEN", "KEY", "CREDENTIAL"}
+
+// WithRedactByName makes Print also mask fields that aren't tagged 'secret=true' but whose name or env variables
+// contain one of the patterns, ignoring case. This is a safety net against forgetting the tag. Without patterns it
+// matches PASSWORD, SECRET, TOKEN, KEY and CREDENTIAL
+func WithRedactByName(patterns ...string) PrintOption {
+	if len(patterns) == 0 {
+		patterns = defaultSensitivePatterns
+	}
+	return func(options *printOptions) {
+		options.sensitivePatterns = patterns
+	}
+}
+
+// isSensitiveName returns true if the field's name or any of its env variables contain one of the patterns, ignoring
+// case
+func isSensitiveName(field reflect.StructField, patterns []string) bool {
+	names := append([]string{field.Name}, envVarNames(field.Tag)...)
+	for _, name := range names {
+		for _, pattern := range patterns {
+			if pattern != "" && strings.Contains(strings.ToUpper(name), strings.ToUpper(pattern)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PrintSorted is the same as Print except that the rows are ordered alphabetically by env variable rather than by
+// their declaration order in the struct
+func PrintSorted(c interface{}) {
+	Print(c, WithSort(true))
+}
+
+func sortRowsByEnvVar(rows []printRow) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].envVar < rows[j].envVar
+	})
+}
+
+// PrintOverrides writes the same table as Print, but only includes fields whose value differs from their declared
+// default. This shows what an operator has actually changed
+func PrintOverrides(w io.Writer, c interface{}) {
+	Print(c, WithWriter(w), WithOverridesOnly(true))
+}
+
+// printRow is a single line of the table written by Print
+type printRow struct {
+	name         string
+	envVar       string
+	value        string
+	defaultValue string
+	overridden   bool
+	empty        bool
+	section      string
+	source       Source
+}
+
+// printRows renders every field in the config in declaration order, obscuring secrets with the mask. Fields whose
+// names match any of the sensitive patterns are treated as secrets too
+func printRows(c interface{}, mask string, sensitivePatterns []string) []printRow {
+	mustBeConfigPointer(c)
+	var rows []printRow
+	// only the names of the set env variables are needed, so there's no point copying the whole environment
+	envLoader := newLoader(os.LookupEnv)
+	defaultsLoader := newLoader(func(string) (string, bool) { return "", false })
+	structType := reflect.ValueOf(c).Elem().Type()
+	structValue := reflect.ValueOf(c).Elem()
+	for _, field := range configFields(structType) {
+		if !isEnvValueSecret(field.Tag) && isSensitiveName(field, sensitivePatterns) {
+			field.Tag += ` secret:"true"`
+		}
+		envVar, _, _ := envLoader.lookupEnv(field.Tag)
+		defaultValue := declaredDefault(field)
+		if field.Type.Kind() == reflect.Bool && isInverted(field.Tag) && field.Tag.Get("defaultFn") == "" {
+			if value, err := parseBool(defaultValue); err == nil {
+				defaultValue = strconv.FormatBool(!value)
+			}
+		}
+		if isEnvValueSecret(field.Tag) {
+			defaultValue = maskSecret(field.Tag, defaultValue, mask)
+		}
+		value := structValue.FieldByIndex(field.Index)
+		rows = append(rows, printRow{
+			name:         field.Name,
+			envVar:       envVar,
+			value:        formatFieldValue(field, value, mask),
+			defaultValue: defaultValue,
+			overridden:   isOverridden(defaultsLoader, field, value),
+			empty:        isEmptyValue(value),
+			section:      field.Tag.Get("section"),
+		})
+	}
+	return rows
+}
+
+// isEmptyValue returns true if the value is the zero value of its type, treating empty slices and maps as zero
+func isEmptyValue(value reflect.Value) bool {
+	if kind := value.Kind(); kind == reflect.Slice || kind == reflect.Map {
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+// declaredDefault returns the default declared for a field, which is either its static default or the name of its
+// default function
+func declaredDefault(field reflect.StructField) string {
+	if name := field.Tag.Get("defaultFn"); name != "" {
+		return name + "()"
+	}
+	defaultValue, _ := staticDefault(field.Tag)
+	return defaultValue
+}
+
+// defaultSection is the heading for fields without a 'section' struct tag when other fields have one
+const defaultSection = "General"
+
+// writeTable writes the rows as an aligned table. If any row has a section, the rows are grouped into a table per
+// section, with the sections in the order they first appear and the rows within them in their original order. The
+// SOURCE column is only written if showSource is true
+func writeTable(w io.Writer, rows []printRow, showSource bool) {
+	var sections []string
+	sectionRows := map[string][]printRow{}
+	for _, row := range rows {
+		section := row.section
+		if section == "" {
+			section = defaultSection
+		}
+		if _, ok := sectionRows[section]; !ok {
+			sections = append(sections, section)
+		}
+		sectionRows[section] = append(sectionRows[section], row)
+	}
+	if len(sections) == 0 || (len(sections) == 1 && sections[0] == defaultSection) {
+		writeSectionTable(w, rows, showSource)
+		return
+	}
+
+	for i, section := range sections {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, section)
+		writeSectionTable(w, sectionRows[section], showSource)
+	}
+}
+
+// jsonRow is a single setting in the output of Print with FormatJSON
+type jsonRow struct {
+	Option  string `json:"option"`
+	EnvVar  string `json:"envVar"`
+	Setting string `json:"setting"`
+	Default string `json:"default"`
+	Section string `json:"section,omitempty"`
+	Source  Source `json:"source,omitempty"`
+}
+
+// writeJSON writes the rows as an indented JSON array
+func writeJSON(w io.Writer, rows []printRow) {
+	jsonRows := make([]jsonRow, len(rows))
+	for i, row := range rows {
+		jsonRows[i] = jsonRow{
+			Option:  row.name,
+			EnvVar:  row.envVar,
+			Setting: row.value,
+			Default: row.defaultValue,
+			Section: row.section,
+			Source:  row.source,
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(jsonRows)
+}
+
+// writeSectionTable writes a single aligned table
+func writeSectionTable(w io.Writer, rows []printRow, showSource bool) {
+	writer := newTableWriter(w)
+
+	if showSource {
+		fmt.Fprint(writer, "OPTION\tENV VAR\tSETTING\tDEFAULT\tSOURCE\n")
 	} else {
-		once.Do(func() { fillConfig(c) })
+		fmt.Fprint(writer, "OPTION\tENV VAR\tSETTING\tDEFAULT\n")
 	}
+	for _, row := range rows {
+		if showSource {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", row.name, row.envVar, row.value, row.defaultValue, row.source)
+			continue
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", row.name, row.envVar, row.value, row.defaultValue)
+	}
+	writer.Flush()
 }
 
-// Print will pretty print the contents of the configuration object. Any struct values with a 'secret=true' struct
-// tag will be obscured if set
-func Print(c interface{}) {
+// newTableWriter returns a writer that aligns tab separated columns into a table
+func newTableWriter(w io.Writer) *tabwriter.Writer {
 	var (
 		minWidth int  = 0
 		tabWidth int  = 0
@@ -30,127 +679,1934 @@ func Print(c interface{}) {
 		padChar  byte = ' '
 		flags    uint = 0
 	)
-	writer := tabwriter.NewWriter(os.Stdout, minWidth, tabWidth, padding, padChar, flags)
+	return tabwriter.NewWriter(w, minWidth, tabWidth, padding, padChar, flags)
+}
 
-	fmt.Fprint(writer, "OPTION\tENV VAR\tSETTING\n")
+// AsMap returns the rendered value of every field keyed by its env variable, with secrets obscured in the same way as
+// Print. Slices and maps are rendered in the same form they are loaded in, so the values can be fed back in as env
+// variables
+func AsMap(c interface{}) map[string]string {
+	return asMap(c, true)
+}
 
-	structType := reflect.ValueOf(c).Elem().Type()
-	structValue := reflect.ValueOf(c).Elem()
-	for i := 0; i < structType.NumField(); i++ {
-		field := structType.Field(i)
-		var stringValue string
-		if isEnvValueSecret(field.Tag) {
+// AsMapUnmasked is the same as AsMap except that secrets are not obscured. Take care not to log the result
+func AsMapUnmasked(c interface{}) map[string]string {
+	return asMap(c, false)
+}
 
-			// It is useful to be able to distinguish between an unset password and a set password
-			if structValue.Field(i).String() == "" {
-				stringValue = ""
-			} else {
-				stringValue = "********"
-			}
+// Redacted returns a shallow copy of the config struct that is safe to log, for example with zap.Any. Secret string
+// fields are obscured with the mask, or left empty if they're not set, and other secret fields are set to their zero
+// value. Slices of structs are copied so that the secrets of their elements are redacted too. The original config is
+// not modified
+func Redacted(c interface{}) interface{} {
+	mustBeConfigPointer(c)
+	redacted := reflect.New(reflect.TypeOf(c).Elem()).Elem()
+	redacted.Set(reflect.ValueOf(c).Elem())
+	redactSecrets(redacted)
+	return redacted.Interface()
+}
 
-		} else {
-			switch field.Type.Kind() {
-			case reflect.String:
-				stringValue = structValue.Field(i).String()
-			case reflect.Int32:
-				stringValue = strconv.Itoa(int(structValue.Field(i).Int()))
-			case reflect.Bool:
-				stringValue = strconv.FormatBool(structValue.Field(i).Bool())
-			case reflect.Slice:
-				stringValue = fmt.Sprintf("%v", structValue.Field(i).Interface().([]string))
-			case reflect.Map:
-				stringValue = fmt.Sprintf("%v", structValue.Field(i).Interface().(map[string]int32))
-			default:
-				panic("GetConfig currently only supports string, int32, bool and map")
+// redactSecrets obscures the secrets of a settable copy of a config struct for Redacted
+func redactSecrets(redacted reflect.Value) {
+	for _, field := range configFields(redacted.Type()) {
+		value := redacted.FieldByIndex(field.Index)
+		switch {
+		case isEnvValueSecret(field.Tag) && value.Kind() == reflect.String:
+			value.SetString(maskSecret(field.Tag, value.String(), getMask()))
+		case isEnvValueSecret(field.Tag):
+			value.Set(reflect.Zero(field.Type))
+		case isStructSlice(field.Type) && !value.IsNil():
+			elements := reflect.MakeSlice(field.Type, value.Len(), value.Len())
+			reflect.Copy(elements, value)
+			for i := 0; i < elements.Len(); i++ {
+				redactSecrets(elements.Index(i))
 			}
+			value.Set(elements)
 		}
+	}
+}
 
-		fmt.Fprintf(writer, "%s\t%s\t%s\n", field.Name, field.Tag.Get("env"), stringValue)
+// Diff compares two instances of the same config type, returning the rendered values from a and b for every env
+// variable whose value differs. Secrets are obscured, so a changed secret is reported even though both of its values
+// may be shown as the mask
+func Diff(a, b interface{}) map[string][2]string {
+	diff := map[string][2]string{}
+	unmaskedA, unmaskedB := AsMapUnmasked(a), AsMapUnmasked(b)
+	maskedA, maskedB := AsMap(a), AsMap(b)
+	for envVar, valueA := range unmaskedA {
+		if valueA != unmaskedB[envVar] {
+			diff[envVar] = [2]string{maskedA[envVar], maskedB[envVar]}
+		}
 	}
-	writer.Flush()
+	return diff
 }
 
-// fillConfig loads the environment
-func fillConfig(c interface{}) {
+func asMap(c interface{}, masked bool) map[string]string {
+	mustBeConfigPointer(c)
+	values := map[string]string{}
 	structType := reflect.ValueOf(c).Elem().Type()
 	structValue := reflect.ValueOf(c).Elem()
-	for i := 0; i < structType.NumField(); i++ {
-		field := structType.Field(i)
+	for _, field := range configFields(structType) {
+		value := encodeValue(field, structValue.FieldByIndex(field.Index), masked)
+		if masked && isEnvValueSecret(field.Tag) {
+			value = maskSecret(field.Tag, value, getMask())
+		}
+		values[envVarName(field.Tag)] = value
+	}
+	return values
+}
+
+// formatFieldValue renders a single field for display, obscuring it with the mask if it's secret
+func formatFieldValue(field reflect.StructField, value reflect.Value, mask string) string {
+	if isEnvValueSecret(field.Tag) {
+		return maskSecret(field.Tag, encodeFieldValue(field, value), mask)
+	}
+	if _, ok := textMarshaler(value); !ok && field.Type != urlType && getEncoding(field.Tag) != "json" {
 		switch field.Type.Kind() {
-		case reflect.String:
-			structValue.Field(i).SetString(getEnvValueString(field.Tag))
-		case reflect.Int32:
-			structValue.Field(i).SetInt(getEnvValueInt(field.Tag))
 		case reflect.Bool:
-			structValue.Field(i).SetBool(getEnvValueBool(field.Tag))
+			return strconv.FormatBool(value.Bool())
 		case reflect.Slice:
-			structValue.Field(i).Set(reflect.ValueOf(getEnvValueStrings(field.Tag)))
+			if field.Type.Elem().Kind() == reflect.Uint8 {
+				return formatBytes(value.Bytes())
+			}
+			// path lists are shown as they are written, such as /a:/b for PATH
+			if isStructSlice(field.Type) || isPathList(field.Tag) {
+				break
+			}
+			return fmt.Sprintf("%v", value.Interface())
+		case reflect.Array:
+			return fmt.Sprintf("%v", value.Interface())
 		case reflect.Map:
-			structValue.Field(i).Set(reflect.ValueOf(getEnvValueIntMap(field.Tag)))
-		default:
-			panic("GetConfig currently only supports string, string slice, int32, bool and map")
+			// fmt prints maps with their keys sorted, so the output is the same on every run
+			return fmt.Sprintf("%v", value.Interface())
 		}
 	}
+	return encodeFieldValue(field, value)
 }
 
-func getEnvValueString(fieldTag reflect.StructTag) string {
+// bytesPreviewLength is the number of bytes shown by formatBytes
+const bytesPreviewLength = 8
 
-	envVar := fieldTag.Get("env")
-	defaultValue := fieldTag.Get("default")
-	var value string
-	value, ok := os.LookupEnv(envVar)
-	if !ok {
-		value = defaultValue
+// formatBytes renders a byte slice as its length and a hex preview, since the raw bytes are often binary data such as
+// keys that would be unreadable in a table
+func formatBytes(value []byte) string {
+	if len(value) == 0 {
+		return ""
 	}
-	return value
+	if len(value) <= bytesPreviewLength {
+		return fmt.Sprintf("%d bytes %x", len(value), value)
+	}
+	return fmt.Sprintf("%d bytes %x...", len(value), value[:bytesPreviewLength])
 }
 
-// isEnvValueSecret returns true if the struct has a tag "secret=true". The value is not case sensitive
-func isEnvValueSecret(fieldTag reflect.StructTag) bool {
-	return strings.ToLower(fieldTag.Get("secret")) == "true"
+// maskSecret obscures a secret value. It is useful to be able to distinguish between an unset password and a set
+// password, so empty values are left empty. A 'reveal=N' struct tag shows the last N characters after the mask, which
+// helps to identify which credential is loaded. Nothing is revealed if the secret isn't longer than N characters. A
+// 'showlen=true' struct tag adds the number of characters in the secret, such as "******** (len=32)"
+func maskSecret(fieldTag reflect.StructTag, value string, mask string) string {
+	if value == "" {
+		return ""
+	}
+	masked := mask
+	characters := []rune(value)
+	if reveal, err := strconv.Atoi(fieldTag.Get("reveal")); err == nil && reveal > 0 && len(characters) > reveal {
+		masked += string(characters[len(characters)-reveal:])
+	}
+	if isTagTrue(fieldTag, "showlen") {
+		masked += fmt.Sprintf(" (len=%d)", len(characters))
+	}
+	return masked
 }
 
-func getEnvValueStrings(fieldTag reflect.StructTag) []string {
-	stringValue := getEnvValueString(fieldTag)
-	if stringValue == "" {
-		return []string{}
-	} else {
-		return strings.Split(stringValue, ",")
-	}
+var (
+	maskMutex sync.RWMutex
+	mask      = "********"
+)
+
+// SetMask changes the placeholder used to obscure secret values, which defaults to "********"
+func SetMask(m string) {
+	maskMutex.Lock()
+	defer maskMutex.Unlock()
+	mask = m
 }
 
-// This method panics if it encounters parsing errors
-func getEnvValueBool(fieldTag reflect.StructTag) bool {
-	valueString := getEnvValueString(fieldTag)
-	result, err := strconv.ParseBool(valueString)
-	if err != nil {
-		panic(fmt.Sprintf("value for %s could not be parsed as a bool", fieldTag.Get("env")))
-	}
-	return result
+func getMask() string {
+	maskMutex.RLock()
+	defer maskMutex.RUnlock()
+	return mask
 }
 
-// This method panics if it encounters parsing errors
-func getEnvValueInt(fieldTag reflect.StructTag) int64 {
-	valueString := getEnvValueString(fieldTag)
-	result, err := strconv.Atoi(valueString)
-	if err != nil {
-		panic(fmt.Sprintf("value for %s could not be parsed as an int32", fieldTag.Get("env")))
+var (
+	loggerMutex sync.RWMutex
+	logger      *zap.Logger
+	// fallbackOutput is where warnings are written if no logger has been configured
+	fallbackOutput io.Writer = os.Stderr
+)
+
+// SetLogger changes the logger used for warnings, such as those about test mode and deprecated env variables. By
+// default the global zap logger is used, which is also restored by passing nil. If the global logger hasn't been
+// configured with zap.ReplaceGlobals either, warnings are written to stderr so they aren't silently dropped
+func SetLogger(l *zap.Logger) {
+	loggerMutex.Lock()
+	defer loggerMutex.Unlock()
+	logger = l
+}
+
+func getLogger() *zap.Logger {
+	loggerMutex.RLock()
+	defer loggerMutex.RUnlock()
+	if logger != nil {
+		return logger
 	}
-	return int64(result)
+	if global := zap.L(); global.Core() != zapcore.NewNopCore() {
+		return global
+	}
+	encoderConfig := zap.NewDevelopmentEncoderConfig()
+	encoderConfig.TimeKey = ""
+	core := zapcore.NewCore(zapcore.NewConsoleEncoder(encoderConfig), zapcore.AddSync(fallbackOutput), zap.InfoLevel)
+	return zap.New(core)
 }
 
-func getEnvValueIntMap(fieldTag reflect.StructTag) map[string]int32 {
-	valueStrings := getEnvValueStrings(fieldTag)
-	valueMap := map[string]int32{}
-	for _, entryString := range valueStrings {
-		pair := strings.Split(entryString, "=")
+// encodeFieldValue renders a single field in the form it would be loaded from an env variable. Secrets within the
+// elements of slices of structs are obscured with the mask
+func encodeFieldValue(field reflect.StructField, value reflect.Value) string {
+	return encodeValue(field, value, true)
+}
 
-		key := pair[0]
-		value, err := strconv.Atoi(pair[1])
+// encodeValue is encodeFieldValue, but only obscures secrets within the elements of slices of structs if maskElements is
+// true
+func encodeValue(field reflect.StructField, value reflect.Value, maskElements bool) string {
+	if getEncoding(field.Tag) == "json" {
+		encoded, err := json.Marshal(value.Interface())
+		if err != nil {
+			return fmt.Sprintf("<%v>", err)
+		}
+		return string(encoded)
+	}
+	if field.Type == urlType {
+		u := value.Interface().(url.URL)
+		return u.String()
+	}
+	if field.Type == durationType {
+		return time.Duration(value.Int()).String()
+	}
+	if marshaler, ok := textMarshaler(value); ok {
+		text, err := marshaler.MarshalText()
 		if err != nil {
-			panic(fmt.Sprintf("Value for %s could not be parsed into a map[string]int32", fieldTag.Get("env")))
+			return fmt.Sprintf("<%v>", err)
+		}
+		return string(text)
+	}
+	if _, ok := getParser(field.Type); ok {
+		return fmt.Sprint(value.Interface())
+	}
+	switch field.Type.Kind() {
+	case reflect.String:
+		if getEncoding(field.Tag) == "base64" {
+			return base64.StdEncoding.EncodeToString([]byte(value.String()))
+		}
+		return value.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if isRune(field.Tag) {
+			return formatRune(rune(value.Int()))
+		}
+		if isByteSize(field.Tag) {
+			return formatByteSize(value.Int())
+		}
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if isByteSize(field.Tag) && value.Uint() <= math.MaxInt64 {
+			return formatByteSize(int64(value.Uint()))
+		}
+		return strconv.FormatUint(value.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		if isPercent(field.Tag) {
+			return formatPercent(value.Float(), field.Type.Bits())
+		}
+		return strconv.FormatFloat(value.Float(), 'g', -1, field.Type.Bits())
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool() != isInverted(field.Tag))
+	case reflect.Slice, reflect.Array:
+		if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Uint8 {
+			if getEncoding(field.Tag) == "base64" {
+				return base64.StdEncoding.EncodeToString(value.Bytes())
+			}
+			return string(value.Bytes())
+		}
+		elements := make([]string, value.Len())
+		for i := range elements {
+			if isStructSlice(field.Type) {
+				elements[i] = encodeStructElement(value.Index(i), maskElements)
+				continue
+			}
+			if isPathList(field.Tag) {
+				elements[i] = fmt.Sprintf("%v", value.Index(i).Interface())
+				continue
+			}
+			elements[i] = quoteElement(fmt.Sprintf("%v", value.Index(i).Interface()), `,"`)
+		}
+		if isPathList(field.Tag) {
+			return strings.Join(elements, string(os.PathListSeparator))
+		}
+		return strings.Join(elements, ",")
+	case reflect.Map:
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keyLess(keys[i], keys[j])
+		})
+		entries := make([]string, len(keys))
+		for i, key := range keys {
+			entries[i] = quoteElement(fmt.Sprintf("%v", key.Interface()), `,="`) + "=" +
+				quoteElement(fmt.Sprintf("%v", value.MapIndex(key).Interface()), `,"`)
+		}
+		if isPathList(field.Tag) {
+			return strings.Join(entries, string(os.PathListSeparator))
+		}
+		return strings.Join(entries, ",")
+	case reflect.Interface:
+		return variantName(field, value)
+	default:
+		return fmt.Sprintf("<%v>", &UnsupportedKindError{Field: field.Name, Kind: field.Type.Kind()})
+	}
+}
+
+// keyLess orders map keys by their value, so that numeric keys are sorted numerically rather than as strings
+func keyLess(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	default:
+		return a.String() < b.String()
+	}
+}
+
+// textMarshaler returns the encoding.TextMarshaler implemented by the field value or its address, if there is one
+func textMarshaler(value reflect.Value) (encoding.TextMarshaler, bool) {
+	if marshaler, ok := value.Interface().(encoding.TextMarshaler); ok {
+		return marshaler, true
+	}
+	if value.CanAddr() {
+		if marshaler, ok := value.Addr().Interface().(encoding.TextMarshaler); ok {
+			return marshaler, true
+		}
+	}
+	return nil, false
+}
+
+// textUnmarshaler returns the encoding.TextUnmarshaler implemented by the field's address, if there is one
+func textUnmarshaler(value reflect.Value) (encoding.TextUnmarshaler, bool) {
+	if !value.CanAddr() {
+		return nil, false
+	}
+	unmarshaler, ok := value.Addr().Interface().(encoding.TextUnmarshaler)
+	return unmarshaler, ok
+}
+
+// loader resolves config values from a source of env variables
+type loader struct {
+	lookup   func(key string) (string, bool)
+	ctx      context.Context
+	resolver SecretResolver
+	resolved map[string]string
+	sources  map[string]Source
+	dir      string
+	// layers are the lookups of each source in order of precedence, when loading from several sources
+	layers []func(key string) (string, bool)
+}
+
+// LoadOption changes how a config is loaded
+type LoadOption func(*loader)
+
+// newLoader returns a loader reading env variables through the given lookup function, which behaves like os.LookupEnv
+func newLoader(lookup func(key string) (string, bool), opts ...LoadOption) *loader {
+	l := &loader{lookup: lookup, ctx: context.Background(), resolved: map[string]string{}, sources: map[string]Source{}}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// load fills the config and then validates it
+func (l *loader) load(c interface{}) error {
+	if err := checkConfigPointer(c); err != nil {
+		return err
+	}
+	return l.loadConfig(c)
+}
+
+// loadConfig checks the definition of a config that is known to be a pointer to a struct, fills it, applies its
+// defaults and validates it. Variants are loaded the same way as the config that holds them
+func (l *loader) loadConfig(c interface{}) error {
+	if err := l.fillCheckedConfig(c); err != nil {
+		return err
+	}
+	return finishConfig(c)
+}
+
+// fillCheckedConfig checks the definition of a config that is known to be a pointer to a struct and fills it, without
+// applying its defaults
+func (l *loader) fillCheckedConfig(c interface{}) error {
+	if err := checkDefinition(reflect.TypeOf(c).Elem()); err != nil {
+		return err
+	}
+	return l.fillConfig(c)
+}
+
+// finishConfig applies the defaults of a filled config and validates it
+func finishConfig(c interface{}) error {
+	if defaulter, ok := c.(Defaulter); ok {
+		defaulter.ApplyDefaults()
+	}
+	if validator, ok := c.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return &ValidationError{Err: err}
+		}
+	}
+	return nil
+}
+
+// fillConfig loads the environment, returning a *FieldErrors listing every field that couldn't be loaded
+func (l *loader) fillConfig(c interface{}) error {
+	structType := reflect.ValueOf(c).Elem().Type()
+	structValue := reflect.ValueOf(c).Elem()
+	for _, field := range retiredFields(structType) {
+		l.warnIfRetired(structType, field)
+	}
+	var errs []error
+	for _, field := range configFields(structType) {
+		if err := l.loadField(field, structValue.FieldByIndex(field.Index)); err != nil {
+			errs = flattenFieldErrors(errs, err)
+			continue
+		}
+		l.warnIfDeprecated(field)
+		l.warnIfDefault(field)
+	}
+	if len(errs) > 0 {
+		return &FieldErrors{Errs: errs}
+	}
+	return nil
+}
+
+// loadField fills a single field and checks the constraints on its value, redacting secrets from any error
+func (l *loader) loadField(field reflect.StructField, fieldValue reflect.Value) error {
+	if isTagTrue(field.Tag, "required") && !hasDefault(field.Tag) && !isStructSlice(field.Type) && !l.isSet(field.Tag) {
+		return fmt.Errorf("field %s is required, but %s isn't set", field.Name, envVarName(field.Tag))
+	}
+	if err := l.fillField(field, fieldValue); err != nil {
+		return l.redactError(field, err)
+	}
+	if isTagTrue(field.Tag, "notzero") && fieldValue.IsZero() {
+		return fmt.Errorf("field %s requires a non-zero value, but %s is zero", field.Name, envVarName(field.Tag))
+	}
+	return checkItems(field, fieldValue)
+}
+
+// warnIfDeprecated logs the migration message from a 'deprecated' struct tag if the field was read from a deprecated
+// env variable. When a field has several env variables only the fallbacks are deprecated, otherwise its single env
+// variable is
+func (l *loader) warnIfDeprecated(field reflect.StructField) {
+	message := field.Tag.Get("deprecated")
+	if message == "" {
+		return
+	}
+	name, _, ok := l.lookupEnv(field.Tag)
+	if !ok {
+		return
+	}
+	names := envVarNames(field.Tag)
+	if len(names) > 1 && name == names[0] {
+		return
+	}
+	getLogger().Warn(fmt.Sprintf("WARNING: env variable %s is deprecated, %s", name, message),
+		zap.String("field", field.Name), zap.String("env", name))
+}
+
+// retiredWarning identifies a warning about a retired env variable of a config type in warnedRetired
+type retiredWarning struct {
+	structType reflect.Type
+	name       string
+}
+
+var (
+	warnedRetiredMutex sync.Mutex
+	warnedRetired      = map[retiredWarning]bool{}
+)
+
+// warnIfRetired logs a warning if any env variable of a retired field of the config type is still set, so operators
+// know to remove it. Retired fields are never loaded, and each variable is only warned about once per config type, so
+// reloading a config doesn't repeat the warning but another config that retired the same variable still gets its own.
+// The value of a 'retired' struct tag is included in the warning unless it's just true
+func (l *loader) warnIfRetired(structType reflect.Type, field reflect.StructField) {
+	note := field.Tag.Get("retired")
+	if isTagTrue(field.Tag, "retired") {
+		note = ""
+	}
+	for _, name := range envVarNames(field.Tag) {
+		if _, ok := l.lookup(name); !ok {
+			continue
+		}
+		key := retiredWarning{structType: structType, name: name}
+		warnedRetiredMutex.Lock()
+		warned := warnedRetired[key]
+		warnedRetired[key] = true
+		warnedRetiredMutex.Unlock()
+		if warned {
+			continue
+		}
+		message := fmt.Sprintf("WARNING: env variable %s is no longer used and can be removed", name)
+		if note != "" {
+			message += ", " + note
+		}
+		getLogger().Warn(message, zap.String("field", field.Name), zap.String("env", name))
+	}
+}
+
+// warnIfDefault logs a warning if a field with a 'warnIfDefault=true' struct tag fell back to its default because none of
+// its env variables are set, which flags important settings that an operator may have forgotten
+func (l *loader) warnIfDefault(field reflect.StructField) {
+	if !isTagTrue(field.Tag, "warnIfDefault") {
+		return
+	}
+	name := envVarName(field.Tag)
+	if l.sources[name] != SourceDefault {
+		return
+	}
+	getLogger().Warn(fmt.Sprintf("WARNING: env variable %s is not set, using the default", name),
+		zap.String("field", field.Name), zap.String("env", name))
+}
+
+// redactError ensures that an error loading a secret field never echoes its value. Errors from parsers such as
+// url.Parse often quote the input, so any occurrence of the raw value in the message is replaced with the mask
+func (l *loader) redactError(field reflect.StructField, err error) error {
+	if !isEnvValueSecret(field.Tag) {
+		return err
+	}
+	value, _ := l.getEnvValueString(field.Tag)
+	if value == "" || !strings.Contains(err.Error(), value) {
+		return err
+	}
+	return errors.New(strings.ReplaceAll(err.Error(), value, getMask()))
+}
+
+// parseError reports that the value of a field could not be parsed as described, such as "parsed as a URL", wrapping
+// the parser's error. Parsers such as url.Parse and json.Unmarshal echo parts of the input, often escaped so that
+// redactError can't find them, so the parser's error is left out for secret fields
+func parseError(fieldTag reflect.StructTag, description string, err error) error {
+	if isEnvValueSecret(fieldTag) {
+		return fmt.Errorf("value for %s could not be %s", envVarName(fieldTag), description)
+	}
+	return fmt.Errorf("value for %s could not be %s: %w", envVarName(fieldTag), description, err)
+}
+
+// fillField loads a single field from the environment
+func (l *loader) fillField(field reflect.StructField, fieldValue reflect.Value) error {
+	if err := checkFlag(field); err != nil {
+		return err
+	}
+	if parse, ok := getParser(field.Type); ok {
+		return l.parseWithParser(field, fieldValue, parse)
+	}
+	if getEncoding(field.Tag) == "json" {
+		return l.getEnvValueJSON(field.Tag, fieldValue)
+	}
+	if unmarshaler, ok := textUnmarshaler(fieldValue); ok {
+		valueString, err := l.getEnvValueString(field.Tag)
+		if err != nil {
+			return err
+		}
+		if err := unmarshaler.UnmarshalText([]byte(valueString)); err != nil {
+			return parseError(field.Tag, "parsed", err)
+		}
+		return nil
+	}
+	if field.Type == durationType {
+		valueString, err := l.getEnvValueString(field.Tag)
+		if err != nil {
+			return err
+		}
+		value, err := time.ParseDuration(strings.TrimSpace(valueString))
+		if err != nil {
+			return fmt.Errorf("value for %s could not be parsed as a duration", envVarName(field.Tag))
+		}
+		fieldValue.SetInt(int64(value))
+		return nil
+	}
+	if field.Type == urlType {
+		valueString, err := l.getEnvValueString(field.Tag)
+		if err != nil {
+			return err
+		}
+		value, err := url.Parse(valueString)
+		if err != nil {
+			return parseError(field.Tag, "parsed as a URL", err)
+		}
+		return assignValue(field, fieldValue, reflect.ValueOf(*value))
+	}
+	switch field.Type.Kind() {
+	case reflect.String:
+		value, err := l.getEnvValueBytes(field.Tag)
+		if err != nil {
+			return err
+		}
+		option, err := matchOneOf(field.Tag, string(value))
+		if err != nil {
+			return err
+		}
+		if err := checkLength(field.Tag, option); err != nil {
+			return err
+		}
+		fieldValue.SetString(option)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if isRune(field.Tag) {
+			if field.Type.Kind() != reflect.Int32 {
+				return fmt.Errorf("kind \"rune\" for %s is not supported on %s fields, it must be a rune", envVarName(field.Tag), field.Type)
+			}
+			value, err := l.getEnvValueRune(field.Tag)
+			if err != nil {
+				return err
+			}
+			fieldValue.SetInt(int64(value))
+			return nil
+		}
+		value, err := l.getEnvValueInt(field.Tag, field.Type.Bits())
+		if err != nil {
+			return err
+		}
+		fieldValue.SetInt(value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, err := l.getEnvValueUint(field.Tag, field.Type.Bits())
+		if err != nil {
+			return err
+		}
+		fieldValue.SetUint(value)
+	case reflect.Float32, reflect.Float64:
+		value, err := l.getEnvValueFloat(field.Tag, field.Type.Bits())
+		if err != nil {
+			return err
+		}
+		fieldValue.SetFloat(value)
+	case reflect.Bool:
+		value, err := l.getEnvValueBool(field.Tag)
+		if err != nil {
+			return err
+		}
+		fieldValue.SetBool(value != isInverted(field.Tag))
+	case reflect.Slice:
+		if field.Type.Elem().Kind() == reflect.Uint8 {
+			if !l.hasValue(field.Tag) {
+				fieldValue.Set(reflect.Zero(field.Type))
+				return nil
+			}
+			value, err := l.getEnvValueBytes(field.Tag)
+			if err != nil {
+				return err
+			}
+			fieldValue.SetBytes(value)
+			return nil
+		}
+		if isStructSlice(field.Type) {
+			return l.fillStructSlice(field, fieldValue)
+		}
+		value, err := l.getEnvValueSlice(field.Tag, field.Type)
+		if err != nil {
+			return err
+		}
+		return assignValue(field, fieldValue, value)
+	case reflect.Array:
+		return l.fillArray(field, fieldValue)
+	case reflect.Map:
+		value, err := l.getEnvValueMap(field.Tag, field.Type)
+		if err != nil {
+			return err
+		}
+		return assignValue(field, fieldValue, value)
+	case reflect.Interface:
+		return l.fillVariant(field, fieldValue)
+	default:
+		return &UnsupportedKindError{Field: field.Name, Kind: field.Type.Kind()}
+	}
+	return nil
+}
+
+// resolveIndirect replaces a value that is entirely a reference to another env variable, written as $NAME or ${NAME},
+// with the value of that variable. Only one level of indirection is followed, and other values are returned unchanged
+func (l *loader) resolveIndirect(fieldTag reflect.StructTag, value string) (string, error) {
+	name, ok := strings.CutPrefix(value, "$")
+	if !ok {
+		return value, nil
+	}
+	if braced, ok := strings.CutPrefix(name, "{"); ok {
+		if name, ok = strings.CutSuffix(braced, "}"); !ok {
+			return value, nil
+		}
+	}
+	if !isEnvVarName(name) {
+		return value, nil
+	}
+	for _, own := range envVarNames(fieldTag) {
+		if name == own {
+			return "", fmt.Errorf("value for %s refers to itself", envVarName(fieldTag))
+		}
+	}
+	target, ok := l.lookup(name)
+	if !ok {
+		return "", fmt.Errorf("value for %s refers to %s, which is not set", envVarName(fieldTag), name)
+	}
+	return target, nil
+}
+
+// isEnvVarName returns true if the name is made of letters, digits and underscores and doesn't start with a digit
+func isEnvVarName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, r := range name {
+		if r != '_' && !('a' <= r && r <= 'z') && !('A' <= r && r <= 'Z') && !('0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// assignValue sets the field to the value, returning a descriptive error rather than panicking if the value can't be
+// assigned to it
+func assignValue(field reflect.StructField, fieldValue reflect.Value, value reflect.Value) error {
+	if !value.IsValid() {
+		return fmt.Errorf("cannot assign nil to field %s of type %s", field.Name, field.Type)
+	}
+	if !fieldValue.CanSet() {
+		return fmt.Errorf("cannot assign %s to field %s of type %s, the field can't be set", value.Type(), field.Name, field.Type)
+	}
+	if !value.Type().AssignableTo(fieldValue.Type()) {
+		return fmt.Errorf("cannot assign %s to field %s of type %s", value.Type(), field.Name, field.Type)
+	}
+	fieldValue.Set(value)
+	return nil
+}
+
+// matchOneOf checks the value against the options listed in the field's 'oneof' struct tag, if it has one, returning
+// the matching option. Options are compared case insensitively if the field has an 'ignoreCase=true' struct tag, in
+// which case the value is normalised to the option's spelling. Empty values are allowed so the field can be left unset
+func matchOneOf(fieldTag reflect.StructTag, value string) (string, error) {
+	oneOf, ok := fieldTag.Lookup("oneof")
+	if !ok || value == "" {
+		return value, nil
+	}
+	options := strings.Split(oneOf, ",")
+	for _, option := range options {
+		if option == value || (isTagTrue(fieldTag, "ignoreCase") && strings.EqualFold(option, value)) {
+			return option, nil
+		}
+	}
+	if isEnvValueSecret(fieldTag) {
+		return "", fmt.Errorf("value for %s is not allowed, it must be one of %s", envVarName(fieldTag),
+			strings.Join(options, ", "))
+	}
+	return "", fmt.Errorf("value %q for %s is not allowed, it must be one of %s", value, envVarName(fieldTag),
+		strings.Join(options, ", "))
+}
+
+// checkLength enforces the limits on the number of characters in the value given by the field's 'minlen' and 'maxlen'
+// struct tags. The error only reports the length, so it's safe to return for secrets. Like 'oneof', empty values are
+// allowed so the field can be left unset, leaving that to the 'required' tag
+func checkLength(fieldTag reflect.StructTag, value string) error {
+	length := utf8.RuneCountInString(value)
+	if minLength, ok := fieldTag.Lookup("minlen"); ok {
+		limit, err := strconv.Atoi(minLength)
+		if err != nil {
+			return fmt.Errorf("minlen for %s must be an integer", envVarName(fieldTag))
+		}
+		if value != "" && length < limit {
+			return fmt.Errorf("value for %s is too short, it must be at least %d characters but is %d",
+				envVarName(fieldTag), limit, length)
+		}
+	}
+	if maxLength, ok := fieldTag.Lookup("maxlen"); ok {
+		limit, err := strconv.Atoi(maxLength)
+		if err != nil {
+			return fmt.Errorf("maxlen for %s must be an integer", envVarName(fieldTag))
+		}
+		if length > limit {
+			return fmt.Errorf("value for %s is too long, it must be at most %d characters but is %d",
+				envVarName(fieldTag), limit, length)
+		}
+	}
+	return nil
+}
+
+// checkItems enforces the limits on the number of items in a slice or map given by the field's 'minitems' and
+// 'maxitems' struct tags
+func checkItems(field reflect.StructField, fieldValue reflect.Value) error {
+	minItems, hasMin := field.Tag.Lookup("minitems")
+	maxItems, hasMax := field.Tag.Lookup("maxitems")
+	if !hasMin && !hasMax {
+		return nil
+	}
+	if kind := field.Type.Kind(); kind != reflect.Slice && kind != reflect.Map {
+		return fmt.Errorf("minitems and maxitems for %s are only supported on slices and maps", envVarName(field.Tag))
+	}
+	count := fieldValue.Len()
+	if hasMin {
+		limit, err := strconv.Atoi(minItems)
+		if err != nil {
+			return fmt.Errorf("minitems for %s must be an integer", envVarName(field.Tag))
+		}
+		if count < limit {
+			return fmt.Errorf("value for %s has too few items, it must have at least %d but has %d",
+				envVarName(field.Tag), limit, count)
+		}
+	}
+	if hasMax {
+		limit, err := strconv.Atoi(maxItems)
+		if err != nil {
+			return fmt.Errorf("maxitems for %s must be an integer", envVarName(field.Tag))
+		}
+		if count > limit {
+			return fmt.Errorf("value for %s has too many items, it must have at most %d but has %d",
+				envVarName(field.Tag), limit, count)
+		}
+	}
+	return nil
+}
+
+// isOverridden returns true if the field's value differs from its declared default, which is loaded by a loader that
+// sees no env variables
+func isOverridden(defaultsLoader *loader, field reflect.StructField, value reflect.Value) bool {
+	defaultValue := reflect.New(field.Type).Elem()
+	if err := defaultsLoader.fillField(field, defaultValue); err != nil {
+		return true
+	}
+	return !reflect.DeepEqual(value.Interface(), defaultValue.Interface())
+}
+
+func (l *loader) getEnvValueString(fieldTag reflect.StructTag) (string, error) {
+	value, source, err := l.getEnvValueSourced(fieldTag)
+	if err != nil {
+		return "", err
+	}
+	l.sources[envVarName(fieldTag)] = source
+	return value, nil
+}
+
+// getEnvValueSourced returns the raw value for a field along with where it came from
+func (l *loader) getEnvValueSourced(fieldTag reflect.StructTag) (string, Source, error) {
+	defaultValue, _ := staticDefault(fieldTag)
+	source := SourceEnv
+	_, value, ok := l.lookupEnv(fieldTag)
+	if !ok && l.dir != "" {
+		var err error
+		value, ok, err = l.readFile(fieldTag)
+		if err != nil {
+			return "", "", err
+		}
+		source = SourceFile
+	}
+	if !ok {
+		source = SourceDefault
+		if name := fieldTag.Get("defaultFn"); name != "" {
+			fn, registered := getDefaultFunc(name)
+			if !registered {
+				return "", "", fmt.Errorf("default function %q for %s is not registered", name, envVarName(fieldTag))
+			}
+			var err error
+			defaultValue, err = fn()
+			if err != nil {
+				return "", "", fmt.Errorf("default function %q for %s failed: %w", name, envVarName(fieldTag), err)
+			}
+		}
+		value = defaultValue
+	}
+	if isTagTrue(fieldTag, "indirect") {
+		var err error
+		if value, err = l.resolveIndirect(fieldTag, value); err != nil {
+			return "", "", err
+		}
+	}
+	if isEnvValueExpanded(fieldTag) {
+		value = os.Expand(value, func(key string) string {
+			expanded, _ := l.lookup(key)
+			return expanded
+		})
+	}
+	if l.resolver != nil && isEnvValueSecret(fieldTag) {
+		resolved, err := l.resolveSecret(envVarName(fieldTag), value)
+		if err != nil {
+			return "", "", err
+		}
+		if resolved != value {
+			source = SourceSecretResolver
+		}
+		value = resolved
+	}
+	value, err := applyTransforms(fieldTag, value)
+	if err != nil {
+		return "", "", err
+	}
+	return value, source, nil
+}
+
+var (
+	defaultFuncsMutex sync.RWMutex
+	defaultFuncs      = map[string]func() (string, error){
+		"hostname": os.Hostname,
+		"numcpu": func() (string, error) {
+			return strconv.Itoa(runtime.NumCPU()), nil
+		},
+	}
+)
+
+// RegisterDefaultFunc registers a function that computes a default value for fields with a 'defaultFn' struct tag of
+// the same name. It is called when none of the field's env variables are set, and takes precedence over the 'default'
+// tag. The "hostname" and "numcpu" functions are registered out of the box
+func RegisterDefaultFunc(name string, fn func() (string, error)) {
+	defaultFuncsMutex.Lock()
+	defer defaultFuncsMutex.Unlock()
+	defaultFuncs[name] = fn
+}
+
+func getDefaultFunc(name string) (func() (string, error), bool) {
+	defaultFuncsMutex.RLock()
+	defer defaultFuncsMutex.RUnlock()
+	fn, ok := defaultFuncs[name]
+	return fn, ok
+}
+
+// envVarNames returns the env variables a field can be loaded from in order of preference. Multiple names can be
+// given as a comma separated list in the 'env' struct tag, which allows an env variable to be renamed gracefully
+func envVarNames(fieldTag reflect.StructTag) []string {
+	return parsedTags(fieldTag).names
+}
+
+// isSet returns true if any of the field's env variables is set, or it has a file in the loader's directory. Files that
+// can't be read count as set, so that fillField reports why
+func (l *loader) isSet(fieldTag reflect.StructTag) bool {
+	if _, _, ok := l.lookupEnv(fieldTag); ok {
+		return true
+	}
+	if l.dir == "" {
+		return false
+	}
+	_, ok, err := l.readFile(fieldTag)
+	return ok || err != nil
+}
+
+// envVarName returns the primary env variable for a field, which is used to identify it in messages
+func envVarName(fieldTag reflect.StructTag) string {
+	return envVarNames(fieldTag)[0]
+}
+
+// lookupEnv returns the value of the first of the field's env variables that is set, along with the name of that
+// variable. If none of them are set the primary name is returned. Variables set to an empty string are treated as
+// unset if the field has an 'emptyAsUnset=true' struct tag. When loading from several sources, every name is tried in
+// one source before moving on to the next, so the order of the sources takes precedence over the order of the names
+func (l *loader) lookupEnv(fieldTag reflect.StructTag) (string, string, bool) {
+	names := envVarNames(fieldTag)
+	emptyAsUnset := isTagTrue(fieldTag, "emptyAsUnset")
+	layers := l.layers
+	if layers == nil {
+		layers = []func(key string) (string, bool){l.lookup}
+	}
+	for _, lookup := range layers {
+		for _, name := range names {
+			if value, ok := lookup(name); ok && (value != "" || !emptyAsUnset) {
+				return name, value, true
+			}
+		}
+	}
+	return names[0], "", false
+}
+
+// checkConfigPointer returns an error unless c is a non-nil pointer to a config struct, which catches the common mistake
+// of passing the struct itself
+func checkConfigPointer(c interface{}) error {
+	value := reflect.ValueOf(c)
+	switch {
+	case !value.IsValid():
+		return errors.New("configstore: expected pointer to struct, got nil")
+	case value.Kind() == reflect.Ptr && value.IsNil():
+		return fmt.Errorf("configstore: expected pointer to struct, got nil %T", c)
+	case value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct:
+		return fmt.Errorf("configstore: expected pointer to struct, got %T", c)
+	}
+	return nil
+}
+
+// mustBeConfigPointer panics with the error from checkConfigPointer, for functions that can't return one
+func mustBeConfigPointer(c interface{}) {
+	if err := checkConfigPointer(c); err != nil {
+		panic(err.Error())
+	}
+}
+
+// configFields returns the fields of the config struct type that are loaded from the environment, in declaration
+// order. The fields of nested structs are included with their Index set to the path for FieldByIndex. Those of
+// anonymous embedded structs are promoted as if they were declared directly, while those of named struct fields are
+// named after the path to them, such as Primary.Host. A 'prefix' struct tag on a nested struct is prepended to the env
+// variables of all the fields within it. Retired fields aren't included
+func configFields(structType reflect.Type) []reflect.StructField {
+	return cachedFields(structType, false)
+}
+
+// retiredFields returns the retired fields of the config struct type, in the same form as configFields
+func retiredFields(structType reflect.Type) []reflect.StructField {
+	return cachedFields(structType, true)
+}
+
+// fieldsCacheKey identifies a list of fields in fieldsCache
+type fieldsCacheKey struct {
+	structType reflect.Type
+	retired    bool
+}
+
+// fieldsCache holds the fields collected for each config type, so that loads, prints and the other functions that walk
+// a config don't repeat the reflection and tag parsing every time. Types never change, so entries never go stale
+var fieldsCache sync.Map
+
+// cachedFields returns the fields collected for the type, collecting them the first time. Callers get their own copy
+// of the list, which they're free to modify
+func cachedFields(structType reflect.Type, retired bool) []reflect.StructField {
+	return append([]reflect.StructField(nil), fieldList(structType, retired)...)
+}
+
+// fieldList returns the cached list of fields collected for the type without copying it, for callers that only read it
+func fieldList(structType reflect.Type, retired bool) []reflect.StructField {
+	key := fieldsCacheKey{structType: structType, retired: retired}
+	cached, ok := fieldsCache.Load(key)
+	if !ok {
+		cached, _ = fieldsCache.LoadOrStore(key, collectFields(structType, retired))
+	}
+	return cached.([]reflect.StructField)
+}
+
+// collectFields walks the fields of the config struct type for configFields, returning either the retired fields or
+// all the others
+func collectFields(structType reflect.Type, retired bool) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if isNestedConfig(field) {
+			prefix := field.Tag.Get("prefix")
+			for _, nested := range collectFields(field.Type, retired) {
+				nested.Index = append([]int{i}, nested.Index...)
+				if !field.Anonymous {
+					nested.Name = field.Name + "." + nested.Name
+				}
+				if prefix != "" {
+					nested.Tag = prefixEnvVars(nested.Tag, prefix)
+				}
+				fields = append(fields, nested)
+			}
+			continue
+		}
+		if !isFieldIgnored(field) && isRetired(field.Tag) == retired {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// isRetired returns true if the field has a 'retired' struct tag, or a 'deprecated=true' struct tag, which retires the
+// field rather than giving a migration message
+func isRetired(fieldTag reflect.StructTag) bool {
+	_, ok := fieldTag.Lookup("retired")
+	return ok || isTagTrue(fieldTag, "deprecated")
+}
+
+// fieldTags holds a parsed struct tag. The same handful of tags are read for every field on every load and print, so
+// each distinct tag is only parsed once
+type fieldTags struct {
+	values map[string]string
+	// flags holds whether each value is true, as returned by isTagTrue
+	flags map[string]bool
+	// names are the env variables of the field, which must not be modified
+	names []string
+}
+
+// tagsCache holds the parsed form of each struct tag. Tags are fixed in the code, and the prefixed tags of nested
+// structs are bounded by the config types, so entries never go stale. The indexed tags of slices of structs are cached
+// too, so the cache also grows with the number of elements seen in the environment, though each entry is only stored
+// once however often the config is loaded
+var tagsCache sync.Map
+
+// parsedTags returns the parsed form of the struct tag, parsing it the first time. As with reflect.StructTag.Get, the
+// first value for a key wins, which lets prefixEnvVars and printRows override a key by prepending or appending to it
+func parsedTags(fieldTag reflect.StructTag) *fieldTags {
+	if cached, ok := tagsCache.Load(fieldTag); ok {
+		return cached.(*fieldTags)
+	}
+	tags := &fieldTags{values: map[string]string{}, flags: map[string]bool{}}
+	for _, key := range structTagKeys(fieldTag) {
+		if _, ok := tags.values[key]; ok {
+			continue
+		}
+		value, _ := fieldTag.Lookup(key)
+		tags.values[key] = value
+		flag, err := parseBoolWith(value, defaultTruthyValues, defaultFalsyValues)
+		tags.flags[key] = err == nil && flag
+	}
+	tags.names = strings.Split(tags.values["env"], ",")
+	for i, name := range tags.names {
+		tags.names[i] = strings.TrimSpace(name)
+	}
+	cached, _ := tagsCache.LoadOrStore(fieldTag, tags)
+	return cached.(*fieldTags)
+}
+
+// structTagKeys returns the keys of a struct tag in the conventional key:"value" form, in the order they're written,
+// stopping at the first malformed pair as reflect.StructTag.Lookup does
+func structTagKeys(fieldTag reflect.StructTag) []string {
+	var keys []string
+	tag := string(fieldTag)
+	for tag != "" {
+		tag = strings.TrimLeft(tag, " ")
+		i := 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		keys = append(keys, key)
+		tag = tag[i+1:]
+	}
+	return keys
+}
+
+// isNestedConfig returns true if the field is a struct without an env tag of its own whose fields should be loaded as
+// part of the enclosing config. Anonymous embedded structs are always nested, while named struct fields need a 'prefix'
+// struct tag, which may be empty
+func isNestedConfig(field reflect.StructField) bool {
+	if _, tagged := field.Tag.Lookup("env"); tagged || field.Type.Kind() != reflect.Struct {
+		return false
+	}
+	_, prefixed := field.Tag.Lookup("prefix")
+	return field.Anonymous || (prefixed && field.PkgPath == "")
+}
+
+// prefixEnvVars returns a copy of the field's struct tag with the prefix prepended to each of its env variables. The
+// new env tag is added at the front, where it takes precedence over the original
+func prefixEnvVars(fieldTag reflect.StructTag, prefix string) reflect.StructTag {
+	names := make([]string, len(envVarNames(fieldTag)))
+	for i, name := range envVarNames(fieldTag) {
+		names[i] = prefix + name
+	}
+	return reflect.StructTag(fmt.Sprintf("env:%q %s", strings.Join(names, ","), fieldTag))
+}
+
+// isFieldIgnored returns true if the field shouldn't be loaded from the environment, either because it's explicitly
+// excluded with an 'env=-' struct tag, because it has no env tag at all, or because it's unexported and so can't be set
+func isFieldIgnored(field reflect.StructField) bool {
+	if field.PkgPath != "" {
+		return true
+	}
+	envVar := field.Tag.Get("env")
+	return envVar == "" || envVar == "-"
+}
+
+// isEnvValueExpanded returns true if the struct has a tag "expand=true", in which case ${VAR} and $VAR references in
+// the value or default are replaced with the values of those env variables
+func isEnvValueExpanded(fieldTag reflect.StructTag) bool {
+	return isTagTrue(fieldTag, "expand")
+}
+
+// isBoolStrict returns true if the struct has a tag "strict=true", in which case a bool field only accepts the values
+// understood by strconv.ParseBool
+func isBoolStrict(fieldTag reflect.StructTag) bool {
+	return isTagTrue(fieldTag, "strict")
+}
+
+// defaultTruthyValues and defaultFalsyValues are the spellings parseBool accepts as well as those of strconv.ParseBool,
+// unless they're replaced with SetBoolValues
+var (
+	defaultTruthyValues = []string{"yes", "y", "on"}
+	defaultFalsyValues  = []string{"no", "n", "off"}
+)
+
+var (
+	boolValuesMutex sync.RWMutex
+	truthyValues    = defaultTruthyValues
+	falsyValues     = defaultFalsyValues
+)
+
+// SetBoolValues replaces the spellings that bool fields accept as true and false, such as enabled and disabled, which
+// are compared case insensitively. The values understood by strconv.ParseBool, such as 1, t, true, 0, f and false, are
+// always accepted. By default yes, y and on are true and no, n and off are false, and passing nil for either set
+// restores its default. Fields tagged 'strict=true' ignore these spellings
+func SetBoolValues(truthy, falsy []string) {
+	if truthy == nil {
+		truthy = defaultTruthyValues
+	}
+	if falsy == nil {
+		falsy = defaultFalsyValues
+	}
+	boolValuesMutex.Lock()
+	defer boolValuesMutex.Unlock()
+	truthyValues = lowerAll(truthy)
+	falsyValues = lowerAll(falsy)
+}
+
+// lowerAll returns a lower case copy of the values
+func lowerAll(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, value := range values {
+		lowered[i] = strings.ToLower(strings.TrimSpace(value))
+	}
+	return lowered
+}
+
+// parseBool is a more forgiving strconv.ParseBool. It is case insensitive and as well as 1, t, true, 0, f and false it
+// accepts the spellings set by SetBoolValues, which are yes, y, on, no, n and off by default
+func parseBool(value string) (bool, error) {
+	boolValuesMutex.RLock()
+	truthy, falsy := truthyValues, falsyValues
+	boolValuesMutex.RUnlock()
+	return parseBoolWith(value, truthy, falsy)
+}
+
+// parseBoolWith is parseBool with the given spellings of true and false, which must be lower case
+func parseBoolWith(value string, truthy, falsy []string) (bool, error) {
+	normalized := strings.ToLower(strings.TrimSpace(value))
+	for _, spelling := range truthy {
+		if normalized == spelling {
+			return true, nil
+		}
+	}
+	for _, spelling := range falsy {
+		if normalized == spelling {
+			return false, nil
+		}
+	}
+	return strconv.ParseBool(normalized)
+}
+
+// isEnvValueSecret returns true if the struct has a tag "secret=true"
+func isEnvValueSecret(fieldTag reflect.StructTag) bool {
+	return isTagTrue(fieldTag, "secret")
+}
+
+// isTagTrue returns true if the struct tag is set to any of the true values accepted by parseBool by default, such as
+// true, 1, yes or on. Struct tags are part of the code, so they aren't affected by SetBoolValues. The value is not case
+// sensitive, and a missing or invalid value is false
+func isTagTrue(fieldTag reflect.StructTag, key string) bool {
+	return parsedTags(fieldTag).flags[key]
+}
+
+// getEnvValueBytes returns the raw value for a string or []byte field, decoding it if the struct has an 'encoding'
+// tag. The only supported encoding is base64
+func (l *loader) getEnvValueBytes(fieldTag reflect.StructTag) ([]byte, error) {
+	valueString, err := l.getEnvValueString(fieldTag)
+	if err != nil {
+		return nil, err
+	}
+	switch encodingName := getEncoding(fieldTag); encodingName {
+	case "":
+		return []byte(valueString), nil
+	case "base64":
+		value, err := base64.StdEncoding.DecodeString(valueString)
+		if err != nil {
+			return nil, fmt.Errorf("value for %s could not be decoded as base64: %w", envVarName(fieldTag), err)
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("encoding %q for %s is not supported", encodingName, envVarName(fieldTag))
+	}
+}
+
+// getEnvValueJSON decodes a JSON document into the field, which allows arbitrarily nested structs, slices and maps to
+// be loaded from a single env variable. An empty value leaves the field as its zero value
+func (l *loader) getEnvValueJSON(fieldTag reflect.StructTag, fieldValue reflect.Value) error {
+	valueString, err := l.getEnvValueString(fieldTag)
+	if err != nil {
+		return err
+	}
+	decoded := reflect.New(fieldValue.Type())
+	if strings.TrimSpace(valueString) != "" {
+		if err := json.Unmarshal([]byte(valueString), decoded.Interface()); err != nil {
+			return parseError(fieldTag, "decoded as JSON", err)
+		}
+	}
+	fieldValue.Set(decoded.Elem())
+	return nil
+}
+
+// hasDefault returns true if the field has a static default or a 'defaultFn' struct tag
+func hasDefault(fieldTag reflect.StructTag) bool {
+	_, hasStatic := staticDefault(fieldTag)
+	_, hasFn := parsedTags(fieldTag).values["defaultFn"]
+	return hasStatic || hasFn
+}
+
+// staticDefault returns the default given by the field's 'default' struct tag. Feature flags can use a 'flag=enabled'
+// or 'flag=disabled' struct tag as shorthand for a default of true or false
+func staticDefault(fieldTag reflect.StructTag) (string, bool) {
+	tags := parsedTags(fieldTag)
+	if defaultValue, ok := tags.values["default"]; ok {
+		return defaultValue, true
+	}
+	switch tags.values["flag"] {
+	case "enabled":
+		return "true", true
+	case "disabled":
+		return "false", true
+	}
+	return "", false
+}
+
+// checkFlag returns an error if the field has a 'flag' struct tag that isn't enabled or disabled, or isn't a bool
+func checkFlag(field reflect.StructField) error {
+	flag, ok := field.Tag.Lookup("flag")
+	if !ok {
+		return nil
+	}
+	if (flag != "enabled" && flag != "disabled") || field.Type.Kind() != reflect.Bool {
+		return fmt.Errorf("flag %q for %s is not supported, it must be enabled or disabled on a bool field", flag, envVarName(field.Tag))
+	}
+	return nil
+}
+
+// getEncoding returns the value of the 'encoding' struct tag, in lower case
+func getEncoding(fieldTag reflect.StructTag) string {
+	return strings.ToLower(fieldTag.Get("encoding"))
+}
+
+// getEnvValueStrings splits a comma separated list, unquoting any elements wrapped in double quotes and unescaping
+// commas written as \,. The elements of path lists are returned as they are
+func (l *loader) getEnvValueStrings(fieldTag reflect.StructTag) ([]string, error) {
+	elements, err := l.getEnvValueList(fieldTag)
+	if err != nil || elements == nil || isPathList(fieldTag) {
+		return elements, err
+	}
+	for i, element := range elements {
+		elements[i], err = unquoteElement(element, ",")
+		if err != nil {
+			return nil, fmt.Errorf("element %d of %s has malformed quotes", i, envVarName(fieldTag))
+		}
+	}
+	return elements, nil
+}
+
+// getEnvValueList splits a comma separated list, leaving commas within double quotes or escaped with a backslash
+// intact. The elements are returned as written, with their quotes and escapes. Fields with a 'sep=os' struct tag are
+// split like the PATH variable instead, on the OS path list separator. It distinguishes the three states of a
+// collection, as described by hasValue
+func (l *loader) getEnvValueList(fieldTag reflect.StructTag) ([]string, error) {
+	if sep := fieldTag.Get("sep"); sep != "" && sep != "os" {
+		return nil, fmt.Errorf("separator %q for %s is not supported, the only separator is os", sep, envVarName(fieldTag))
+	}
+	if !l.hasValue(fieldTag) {
+		return nil, nil
+	}
+	stringValue, err := l.getEnvValueString(fieldTag)
+	if err != nil {
+		return nil, err
+	}
+	if stringValue == "" {
+		return []string{}, nil
+	}
+	if isPathList(fieldTag) {
+		return filepath.SplitList(stringValue), nil
+	}
+	return splitQuoted(stringValue, ',', -1), nil
+}
+
+// hasValue returns false if the field has no value to load, because none of its env variables or files are set and it
+// has no default. This distinguishes the three states of every collection, whether it's a slice, map or []byte: with no
+// value it's left nil, otherwise the env value or default is parsed by the same rules. An env variable that is set but
+// empty gives an empty collection rather than the default, unless the field has an 'emptyAsUnset=true' struct tag. A
+// field with no value is recorded as coming from its default, as its value isn't read through getEnvValueString
+func (l *loader) hasValue(fieldTag reflect.StructTag) bool {
+	if _, _, ok := l.lookupEnv(fieldTag); ok || hasDefault(fieldTag) {
+		return true
+	}
+	if l.dir != "" {
+		if _, ok, err := l.readFile(fieldTag); ok || err != nil {
+			return true
+		}
+	}
+	l.sources[envVarName(fieldTag)] = SourceDefault
+	return false
+}
+
+// isPathList returns true if the field has a 'sep=os' struct tag, marking a list separated by os.PathListSeparator
+func isPathList(fieldTag reflect.StructTag) bool {
+	return fieldTag.Get("sep") == "os"
+}
+
+// splitQuoted splits the value around each separator that isn't within double quotes or escaped with a backslash, into
+// at most n parts if n is positive. Quotes only open at the start of a part or after an '=', so that keys and values
+// can be quoted separately, and backslashes escape the next character within them. The parts are returned as written,
+// with their quotes and escapes
+func splitQuoted(value string, separator byte, n int) []string {
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(value); i++ {
+		switch {
+		case quoted && value[i] == '\\':
+			i++
+		case !quoted && value[i] == '\\' && i+1 < len(value) && value[i+1] == separator:
+			i++
+		case quoted && value[i] == '"':
+			quoted = false
+		case !quoted && value[i] == '"' && (i == start || value[i-1] == '='):
+			quoted = true
+		case !quoted && value[i] == separator && (n <= 0 || len(parts) < n-1):
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+// unquoteElement removes the double quotes around an element of a list, if it has them, interpreting Go escape
+// sequences within. Elements without quotes have a backslash removed from before any of the escaped characters, and
+// other backslashes are left as they are. A quote that opens an element but is never closed is an error, as
+// splitQuoted would otherwise have swallowed the elements after it
+func unquoteElement(element string, escaped string) (string, error) {
+	if !strings.HasPrefix(element, `"`) {
+		return unescapeElement(element, escaped), nil
+	}
+	if len(element) < 2 || element[len(element)-1] != '"' {
+		return "", strconv.ErrSyntax
+	}
+	return strconv.Unquote(element)
+}
+
+// unescapeElement removes the backslash from before each of the escaped characters in an unquoted element
+func unescapeElement(element string, escaped string) string {
+	if !strings.Contains(element, "\\") {
+		return element
+	}
+	var b strings.Builder
+	for i := 0; i < len(element); i++ {
+		if element[i] == '\\' && i+1 < len(element) && strings.IndexByte(escaped, element[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(element[i])
+	}
+	return b.String()
+}
+
+// quoteElement double quotes an element of a list if it contains any of the special characters, so that it can be
+// loaded back by getEnvValueStrings or getEnvValueMap
+func quoteElement(element string, special string) string {
+	if strings.ContainsAny(element, special) {
+		return strconv.Quote(element)
+	}
+	return element
+}
+
+func (l *loader) getEnvValueBool(fieldTag reflect.StructTag) (bool, error) {
+	valueString, err := l.getEnvValueString(fieldTag)
+	if err != nil {
+		return false, err
+	}
+	parse := parseBool
+	if isBoolStrict(fieldTag) {
+		parse = strconv.ParseBool
+	}
+	result, err := parse(valueString)
+	if err != nil {
+		return false, fmt.Errorf("value for %s could not be parsed as a bool", envVarName(fieldTag))
+	}
+	return result, nil
+}
+
+// getEnvValueInt parses the value for an integer field, returning an error if it doesn't fit in the given number of
+// bits rather than letting it silently overflow when it's assigned
+func (l *loader) getEnvValueInt(fieldTag reflect.StructTag, bitSize int) (int64, error) {
+	valueString, err := l.getEnvValueString(fieldTag)
+	if err != nil {
+		return 0, err
+	}
+	valueString = strings.TrimSpace(valueString)
+	var result int64
+	if isByteSize(fieldTag) {
+		result, err = parseByteSize(valueString)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return 0, fmt.Errorf("value for %s could not be parsed as a byte size", envVarName(fieldTag))
+		}
+	} else {
+		result, err = strconv.ParseInt(valueString, integerBase(valueString), 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return 0, fmt.Errorf("value for %s could not be parsed as an integer", envVarName(fieldTag))
+		}
+	}
+	minValue, maxValue := int64(-1)<<(bitSize-1), int64(1)<<(bitSize-1)-1
+	if err != nil || result < minValue || result > maxValue {
+		return 0, fmt.Errorf("value for %s is out of range, it must be between %d and %d", envVarName(fieldTag), minValue, maxValue)
+	}
+	return result, nil
+}
+
+// getEnvValueUint parses an unsigned integer of the given bit size, rejecting negative values. Like getEnvValueInt it
+// accepts byte sizes if the field has a 'unit=bytes' struct tag
+func (l *loader) getEnvValueUint(fieldTag reflect.StructTag, bitSize int) (uint64, error) {
+	valueString, err := l.getEnvValueString(fieldTag)
+	if err != nil {
+		return 0, err
+	}
+	valueString = strings.TrimSpace(valueString)
+	if strings.HasPrefix(valueString, "-") {
+		return 0, fmt.Errorf("value for %s must not be negative", envVarName(fieldTag))
+	}
+	var result uint64
+	if isByteSize(fieldTag) {
+		var size int64
+		size, err = parseByteSize(valueString)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return 0, fmt.Errorf("value for %s could not be parsed as a byte size", envVarName(fieldTag))
+		}
+		result = uint64(size)
+	} else {
+		result, err = strconv.ParseUint(valueString, integerBase(valueString), 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return 0, fmt.Errorf("value for %s could not be parsed as an integer", envVarName(fieldTag))
+		}
+	}
+	maxValue := uint64(1)<<(bitSize-1)<<1 - 1
+	if err != nil || result > maxValue {
+		return 0, fmt.Errorf("value for %s is out of range, it must be between 0 and %d", envVarName(fieldTag), maxValue)
+	}
+	return result, nil
+}
+
+// isRune returns true if the field has a 'kind=rune' struct tag, in which case it's assigned a single character rather
+// than parsed as a number. The tag is needed because rune is an alias of int32
+func isRune(fieldTag reflect.StructTag) bool {
+	return fieldTag.Get("kind") == "rune"
+}
+
+// getEnvValueRune parses the value for a rune field, which must be a single character. Characters that are awkward to
+// set, such as a tab, can be written as a Go escape sequence like \t. Whitespace isn't trimmed, so a space can be used
+func (l *loader) getEnvValueRune(fieldTag reflect.StructTag) (rune, error) {
+	valueString, err := l.getEnvValueString(fieldTag)
+	if err != nil {
+		return 0, err
+	}
+	if r, size := utf8.DecodeRuneInString(valueString); size == len(valueString) && (r != utf8.RuneError || size > 1) {
+		return r, nil
+	}
+	if strings.HasPrefix(valueString, `\`) {
+		r, _, tail, err := strconv.UnquoteChar(valueString, '\'')
+		if err == nil && tail == "" {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("value for %s must be a single character", envVarName(fieldTag))
+}
+
+// formatRune renders a rune as its character, escaping characters that can't be printed so the result can be loaded
+// back by getEnvValueRune
+func formatRune(r rune) string {
+	if unicode.IsPrint(r) {
+		return string(r)
+	}
+	quoted := strconv.QuoteRune(r)
+	return quoted[1 : len(quoted)-1]
+}
+
+// getEnvValueFloat parses the value for a float field. If the field has a 'unit=percent' struct tag, a value ending in
+// % is divided by 100, so 10% gives 0.1, while values without the % are taken as they are
+func (l *loader) getEnvValueFloat(fieldTag reflect.StructTag, bitSize int) (float64, error) {
+	valueString, err := l.getEnvValueString(fieldTag)
+	if err != nil {
+		return 0, err
+	}
+	valueString = strings.TrimSpace(valueString)
+	percent := false
+	if isPercent(fieldTag) {
+		valueString, percent = strings.CutSuffix(valueString, "%")
+		valueString = strings.TrimSpace(valueString)
+	}
+	result, err := strconv.ParseFloat(valueString, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		if percent {
+			return 0, fmt.Errorf("value for %s could not be parsed as a percentage", envVarName(fieldTag))
+		}
+		return 0, fmt.Errorf("value for %s could not be parsed as a number", envVarName(fieldTag))
+	}
+	if percent {
+		result /= 100
+	}
+	if err != nil || (bitSize == 32 && math.Abs(result) > math.MaxFloat32 && !math.IsInf(result, 0)) {
+		return 0, fmt.Errorf("value for %s is out of range", envVarName(fieldTag))
+	}
+	return result, nil
+}
+
+// isPercent returns true if the float field has a 'unit=percent' struct tag, in which case it accepts values such as 10%
+func isPercent(fieldTag reflect.StructTag) bool {
+	return strings.ToLower(fieldTag.Get("unit")) == "percent"
+}
+
+// formatPercent renders a fraction as a percentage, such as 0.1 as 10%. It uses the fewest digits that parse back to
+// the same value, which avoids the noise of multiplying by 100, as in 7.000000000000001%
+func formatPercent(value float64, bitSize int) string {
+	for precision := 1; precision < 17; precision++ {
+		formatted := strconv.FormatFloat(value*100, 'g', precision, 64)
+		parsed, err := strconv.ParseFloat(formatted, 64)
+		if err == nil && roundFloat(parsed/100, bitSize) == roundFloat(value, bitSize) {
+			return formatted + "%"
+		}
+	}
+	return strconv.FormatFloat(value*100, 'g', -1, 64) + "%"
+}
+
+// roundFloat rounds a value to the precision of a float with the given bit size
+func roundFloat(value float64, bitSize int) float64 {
+	if bitSize == 32 {
+		return float64(float32(value))
+	}
+	return value
+}
+
+// isInverted returns true if the bool field has an 'invert=true' struct tag, in which case it is assigned the opposite of
+// its env value, so DISABLE_CACHE=true gives EnableCache=false
+func isInverted(fieldTag reflect.StructTag) bool {
+	return isTagTrue(fieldTag, "invert")
+}
+
+// integerBase returns the base to parse an integer in. Values with a 0x, 0o or 0b prefix are hexadecimal, octal or
+// binary, and underscores may separate digits as in Go source, such as 10_485_760. Everything else is decimal, so a
+// leading zero doesn't make a value octal
+func integerBase(value string) int {
+	digits := strings.TrimLeft(value, "+-")
+	if len(digits) > 1 && digits[0] == '0' && strings.IndexByte("xXoObB", digits[1]) >= 0 {
+		return 0
+	}
+	if strings.Contains(digits, "_") && !strings.HasPrefix(digits, "0") {
+		return 0
+	}
+	return 10
+}
+
+// isByteSize returns true if the struct has a tag "unit=bytes", in which case an integer field accepts sizes such as
+// 10MB
+func isByteSize(fieldTag reflect.StructTag) bool {
+	return strings.ToLower(fieldTag.Get("unit")) == "bytes"
+}
+
+// byteUnits are the suffixes understood by parseByteSize, largest first. Following the common convention for
+// configuring memory and buffer sizes, KB and KiB both mean 1024 bytes
+var byteUnits = []struct {
+	suffixes   []string
+	multiplier int64
+}{
+	{[]string{"tib", "tb"}, 1 << 40},
+	{[]string{"gib", "gb"}, 1 << 30},
+	{[]string{"mib", "mb"}, 1 << 20},
+	{[]string{"kib", "kb"}, 1 << 10},
+	{[]string{"b"}, 1},
+}
+
+// parseByteSize parses a size such as 512, 64KB or 10MiB into a number of bytes. Units are case insensitive. Numbers
+// with a 0x, 0o or 0b prefix are taken as a plain number of bytes, since b is also a hex digit, so 0x1B is 27 bytes
+func parseByteSize(value string) (int64, error) {
+	normalized := strings.ToLower(strings.TrimSpace(value))
+	multiplier := int64(1)
+	if digits := strings.TrimLeft(normalized, "+-"); len(digits) > 1 && digits[0] == '0' &&
+		strings.IndexByte("xob", digits[1]) >= 0 {
+		return strconv.ParseInt(normalized, 0, 64)
+	}
+units:
+	for _, unit := range byteUnits {
+		for _, suffix := range unit.suffixes {
+			if strings.HasSuffix(normalized, suffix) {
+				normalized = strings.TrimSpace(strings.TrimSuffix(normalized, suffix))
+				multiplier = unit.multiplier
+				break units
+			}
+		}
+	}
+	size, err := strconv.ParseInt(normalized, integerBase(normalized), 64)
+	if err != nil {
+		return 0, err
+	}
+	if size > math.MaxInt64/multiplier || size < math.MinInt64/multiplier {
+		return 0, strconv.ErrRange
+	}
+	return size * multiplier, nil
+}
+
+// formatByteSize renders a number of bytes using the largest unit that represents it exactly, so that the result can
+// be parsed back by parseByteSize
+func formatByteSize(size int64) string {
+	if size == 0 {
+		return "0B"
+	}
+	for _, unit := range byteUnits {
+		if size%unit.multiplier == 0 {
+			return fmt.Sprintf("%d%s", size/unit.multiplier, strings.ToUpper(unit.suffixes[len(unit.suffixes)-1]))
+		}
+	}
+	return fmt.Sprintf("%dB", size)
+}
+
+// getEnvValueSlice parses a comma separated list into a slice of the given type, with each element parsed according to
+// its kind
+func (l *loader) getEnvValueSlice(fieldTag reflect.StructTag, sliceType reflect.Type) (reflect.Value, error) {
+	valueStrings, err := l.getEnvValueStrings(fieldTag)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if valueStrings == nil {
+		return reflect.Zero(sliceType), nil
+	}
+	slice := reflect.MakeSlice(sliceType, len(valueStrings), len(valueStrings))
+	for i, elementString := range valueStrings {
+		element, err := parseElement(sliceType.Elem(), elementString)
+		if errors.Is(err, errUnsupportedElement) {
+			return reflect.Value{}, fmt.Errorf("%s for %s is not supported, %w", sliceType, envVarName(fieldTag), err)
+		} else if err != nil {
+			return reflect.Value{}, fmt.Errorf("element %d of %s could not be parsed as a %s", i, envVarName(fieldTag), sliceType.Elem())
+		}
+		slice.Index(i).Set(element)
+	}
+	return slice, nil
+}
+
+// fillArray fills a fixed size array from a comma separated list, which must have exactly as many elements as the
+// array. The array is zeroed if the field isn't set
+func (l *loader) fillArray(field reflect.StructField, fieldValue reflect.Value) error {
+	value, err := l.getEnvValueSlice(field.Tag, reflect.SliceOf(field.Type.Elem()))
+	if err != nil {
+		return err
+	}
+	array := reflect.New(field.Type).Elem()
+	if !value.IsNil() {
+		if value.Len() != field.Type.Len() {
+			return fmt.Errorf("value for %s must have exactly %d elements but has %d", envVarName(field.Tag),
+				field.Type.Len(), value.Len())
+		}
+		reflect.Copy(array, value)
+	}
+	return assignValue(field, fieldValue, array)
+}
+
+// isStructSlice returns true if the type is a slice of config structs, which are loaded from indexed env variables
+func isStructSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct && len(fieldList(t.Elem(), false)) > 0
+}
+
+// fillStructSlice fills a slice of structs from indexed env variables, so that the fields of the first element of a
+// slice loaded from SERVER are read from SERVER_0_HOST, SERVER_0_PORT and so on. Indices are probed from 0, and the
+// slice ends at the first index where none of the element's env variables are set. The slice is left nil if there
+// are no elements
+func (l *loader) fillStructSlice(field reflect.StructField, fieldValue reflect.Value) error {
+	slice := reflect.Zero(field.Type)
+	var errs []error
+	for i := 0; ; i++ {
+		elementFields := indexedFields(field, i)
+		if !l.isElementSet(elementFields) {
+			break
+		}
+
+		element := reflect.New(field.Type.Elem()).Elem()
+		for _, elementField := range elementFields {
+			if err := l.loadField(elementField, element.FieldByIndex(elementField.Index)); err != nil {
+				errs = flattenFieldErrors(errs, err)
+				continue
+			}
+			l.warnIfDeprecated(elementField)
+			l.warnIfDefault(elementField)
+		}
+		slice = reflect.Append(slice, element)
+	}
+	if len(errs) > 0 {
+		return &FieldErrors{Errs: errs}
+	}
+	return assignValue(field, fieldValue, slice)
+}
+
+// isElementSet returns true if any of the env variables of the fields of an element of a slice of structs is set
+func (l *loader) isElementSet(elementFields []reflect.StructField) bool {
+	for _, elementField := range elementFields {
+		if _, _, ok := l.lookupEnv(elementField.Tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// indexedFields returns the fields of the element at the index of a slice of structs, with the indexed prefix added to
+// their env variables and the index to their names, such as Servers[0].Host
+func indexedFields(field reflect.StructField, index int) []reflect.StructField {
+	prefix := fmt.Sprintf("%s_%d_", envVarName(field.Tag), index)
+	fields := configFields(field.Type.Elem())
+	for i := range fields {
+		fields[i].Tag = prefixEnvVars(fields[i].Tag, prefix)
+		fields[i].Name = fmt.Sprintf("%s[%d].%s", field.Name, index, fields[i].Name)
+	}
+	return fields
+}
+
+// isIndexedEnvVar returns true if the name is the indexed env variable of a field within one of the slices of structs
+func isIndexedEnvVar(name string, structSlices []reflect.StructField) bool {
+	for _, field := range structSlices {
+		rest, ok := strings.CutPrefix(name, envVarName(field.Tag)+"_")
+		if !ok {
+			continue
+		}
+		index, elementName, ok := strings.Cut(rest, "_")
+		if !ok || index == "" || strings.Trim(index, "0123456789") != "" {
+			continue
+		}
+		var nested []reflect.StructField
+		for _, elementField := range configFields(field.Type.Elem()) {
+			for _, known := range envVarNames(elementField.Tag) {
+				if known == elementName {
+					return true
+				}
+			}
+			if isStructSlice(elementField.Type) {
+				nested = append(nested, elementField)
+			}
+		}
+		if isIndexedEnvVar(elementName, nested) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeStructElement renders an element of a slice of structs, obscuring its secrets with the mask if masked is true
+func encodeStructElement(element reflect.Value, masked bool) string {
+	var parts []string
+	for _, field := range configFields(element.Type()) {
+		value := encodeValue(field, element.FieldByIndex(field.Index), masked)
+		if masked && isEnvValueSecret(field.Tag) {
+			value = maskSecret(field.Tag, value, getMask())
+		}
+		parts = append(parts, field.Name+":"+value)
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
+
+// errUnsupportedElement is returned by parseElement for element types it can't parse
+var errUnsupportedElement = errors.New("elements must be strings, bools, integers, floats or durations")
+
+// isElementType returns true if parseElement supports the type
+func isElementType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// parseElement parses a single element of a slice or map according to its kind. Range errors from strconv are
+// returned as is so callers can report them
+func parseElement(elementType reflect.Type, value string) (reflect.Value, error) {
+	element := reflect.New(elementType).Elem()
+	if elementType == durationType {
+		result, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		element.SetInt(int64(result))
+		return element, nil
+	}
+	switch elementType.Kind() {
+	case reflect.String:
+		element.SetString(value)
+	case reflect.Bool:
+		result, err := parseBool(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		element.SetBool(result)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = strings.TrimSpace(value)
+		result, err := strconv.ParseInt(value, integerBase(value), elementType.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		element.SetInt(result)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = strings.TrimSpace(value)
+		result, err := strconv.ParseUint(value, integerBase(value), elementType.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		element.SetUint(result)
+	case reflect.Float32, reflect.Float64:
+		result, err := strconv.ParseFloat(strings.TrimSpace(value), elementType.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		element.SetFloat(result)
+	default:
+		return reflect.Value{}, errUnsupportedElement
+	}
+	return element, nil
+}
+
+// getEnvValueMap parses a list of key=value pairs into a map of the given type. The keys and values are both parsed
+// according to their kind, so keys can be any type that can be an element, such as the ints of map[int]string
+func (l *loader) getEnvValueMap(fieldTag reflect.StructTag, mapType reflect.Type) (reflect.Value, error) {
+	if !isElementType(mapType.Key()) {
+		return reflect.Value{}, fmt.Errorf("%s for %s is not supported, map keys must be strings, bools, integers, floats or durations",
+			mapType, envVarName(fieldTag))
+	}
+	valueStrings, err := l.getEnvValueList(fieldTag)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if valueStrings == nil {
+		return reflect.Zero(mapType), nil
+	}
+	valueMap := reflect.MakeMapWithSize(mapType, len(valueStrings))
+	for _, entryString := range valueStrings {
+		// entries are substrings of the value, which redactError can't find, so they're left out of errors for secrets
+		entry := fmt.Sprintf("%q ", entryString)
+		if isEnvValueSecret(fieldTag) {
+			entry = ""
+		}
+		pair := splitQuoted(entryString, '=', 2)
+		if len(pair) != 2 {
+			return reflect.Value{}, fmt.Errorf("malformed map entry %sin %s, expected key=value", entry, envVarName(fieldTag))
+		}
+		keyString, keyErr := unquoteElement(pair[0], ",=")
+		valueString, valueErr := unquoteElement(pair[1], ",=")
+		if keyErr != nil || valueErr != nil {
+			return reflect.Value{}, fmt.Errorf("map entry %sin %s has malformed quotes", entry, envVarName(fieldTag))
+		}
+
+		key, err := parseElement(mapType.Key(), keyString)
+		if err != nil && isEnvValueSecret(fieldTag) {
+			return reflect.Value{}, fmt.Errorf("a key in %s could not be parsed as a %s", envVarName(fieldTag), mapType.Key())
+		} else if err != nil {
+			return reflect.Value{}, fmt.Errorf("key %q in %s could not be parsed as a %s", keyString, envVarName(fieldTag), mapType.Key())
+		}
+		value, err := parseElement(mapType.Elem(), valueString)
+		if errors.Is(err, errUnsupportedElement) {
+			return reflect.Value{}, fmt.Errorf("%s for %s is not supported, %w", mapType, envVarName(fieldTag), err)
+		} else if errors.Is(err, strconv.ErrRange) {
+			return reflect.Value{}, fmt.Errorf("value for %s is out of range for a %s", envVarName(fieldTag), mapType)
+		} else if err != nil {
+			return reflect.Value{}, fmt.Errorf("value for %s could not be parsed into a %s", envVarName(fieldTag), mapType)
 		}
-		valueMap[key] = int32(value)
+		valueMap.SetMapIndex(key, value)
 	}
-	return valueMap
+	return valueMap, nil
 }
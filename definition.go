@@ -0,0 +1,46 @@
+package configstore
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	checkedDefinitionsMutex sync.RWMutex
+	checkedDefinitions      = map[reflect.Type]error{}
+)
+
+// checkDefinition lints the struct tags of a config type for mistakes such as a required field with a default, which
+// can never be missing, or two fields reading the same env variable, including through the prefixes of nested structs.
+// These are mistakes in the code rather than the environment, so each type is only checked the first time it's loaded
+func checkDefinition(structType reflect.Type) error {
+	checkedDefinitionsMutex.RLock()
+	err, ok := checkedDefinitions[structType]
+	checkedDefinitionsMutex.RUnlock()
+	if ok {
+		return err
+	}
+	var errs []error
+	owners := map[string]string{}
+	for _, field := range configFields(structType) {
+		if isTagTrue(field.Tag, "required") && hasDefault(field.Tag) {
+			errs = append(errs, fmt.Errorf("field %s is required but has a default, so %s can never be missing",
+				field.Name, envVarName(field.Tag)))
+		}
+		for _, name := range envVarNames(field.Tag) {
+			if owner, ok := owners[name]; ok && owner != field.Name {
+				errs = append(errs, fmt.Errorf("fields %s and %s both read %s", owner, field.Name, name))
+				continue
+			}
+			owners[name] = field.Name
+		}
+	}
+	if len(errs) > 0 {
+		err = &FieldErrors{Errs: errs}
+	}
+	checkedDefinitionsMutex.Lock()
+	defer checkedDefinitionsMutex.Unlock()
+	checkedDefinitions[structType] = err
+	return err
+}
@@ -0,0 +1,58 @@
+package configstore
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type conflictingStruct struct {
+	Region  string `env:"CONFLICT_REGION" required:"true" default:"eu"`
+	Zone    string `env:"CONFLICT_ZONE" required:"true" default:""`
+	Host    string `env:"CONFLICT_HOST" required:"true"`
+	Timeout int32  `env:"CONFLICT_TIMEOUT" required:"true" default:"30"`
+	Cloud   string `env:"CONFLICT_CLOUD" required:"true" defaultFn:"test_region"`
+	Beta    bool   `env:"CONFLICT_BETA" required:"true" flag:"enabled"`
+}
+
+func TestLoadRejectsRequiredWithDefault(t *testing.T) {
+	var c conflictingStruct
+	err := LoadFromMap(&c, map[string]string{"CONFLICT_REGION": "us", "CONFLICT_TIMEOUT": "10"})
+	assert.EqualError(t, err, "5 config fields could not be loaded:\n"+
+		"  - field Region is required but has a default, so CONFLICT_REGION can never be missing\n"+
+		"  - field Zone is required but has a default, so CONFLICT_ZONE can never be missing\n"+
+		"  - field Timeout is required but has a default, so CONFLICT_TIMEOUT can never be missing\n"+
+		"  - field Cloud is required but has a default, so CONFLICT_CLOUD can never be missing\n"+
+		"  - field Beta is required but has a default, so CONFLICT_BETA can never be missing")
+	assert.Equal(t, conflictingStruct{}, c)
+
+	// the result is cached, so later loads report the same error
+	assert.Equal(t, err, LoadFromMap(&c, nil))
+}
+
+func TestCheckDefinition(t *testing.T) {
+	var c struct {
+		Host string `env:"DEFINITION_HOST" required:"true"`
+		Port int32  `env:"DEFINITION_PORT" default:"80"`
+	}
+	assert.NoError(t, LoadFromMap(&c, map[string]string{"DEFINITION_HOST": "db"}))
+	assert.Equal(t, "db", c.Host)
+	assert.Equal(t, int32(80), c.Port)
+}
+
+type duplicateDBConfig struct {
+	Host string `env:"HOST"`
+}
+
+type duplicateStruct struct {
+	Primary   duplicateDBConfig `prefix:"DUPLICATE_"`
+	Host      string            `env:"DUPLICATE_HOST"`
+	Port      int32             `env:"DUPLICATE_PORT" default:"80"`
+	AdminPort int32             `env:"DUPLICATE_ADMIN_PORT,DUPLICATE_PORT" default:"81"`
+}
+
+func TestLoadRejectsDuplicateEnvVars(t *testing.T) {
+	var c duplicateStruct
+	assert.EqualError(t, LoadFromMap(&c, nil), "2 config fields could not be loaded:\n"+
+		"  - fields Primary.Host and Host both read DUPLICATE_HOST\n"+
+		"  - fields Port and AdminPort both read DUPLICATE_PORT")
+}
@@ -0,0 +1,50 @@
+package configstore
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// LoadFromDir is the same as Load, but also reads values from files in the directory, such as a Kubernetes Secret or
+// ConfigMap mounted as a volume. A field's file is named after its env variable, or given by a 'file' struct tag,
+// which is relative to the directory unless it's absolute. The contents are trimmed of surrounding whitespace. Env
+// variables take precedence over files, and fields without a file fall back to their default. It accepts the same
+// options as LoadContext
+func LoadFromDir(c interface{}, dir string, opts ...LoadOption) error {
+	return newLoader(snapshotEnv(), append(opts, WithDir(dir))...).load(c)
+}
+
+// WithDir also reads values from files in the directory, as LoadFromDir does. It lets LoadWithReport and the other
+// functions that accept options report values that came from a file as SourceFile
+func WithDir(dir string) LoadOption {
+	return func(l *loader) {
+		l.dir = dir
+	}
+}
+
+// readFile reads the value of a field from the first of its files that exists in the loader's directory
+func (l *loader) readFile(fieldTag reflect.StructTag) (string, bool, error) {
+	names := envVarNames(fieldTag)
+	if file := fieldTag.Get("file"); file != "" {
+		names = []string{file}
+	}
+	for _, name := range names {
+		path := name
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(l.dir, name)
+		}
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return "", false, fmt.Errorf("value for %s could not be read from %s: %w", envVarName(fieldTag), path, err)
+		}
+		return strings.TrimSpace(string(data)), true, nil
+	}
+	return "", false, nil
+}
@@ -0,0 +1,53 @@
+package configstore
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type dirStruct struct {
+	Host     string `env:"DIR_HOST" default:"localhost"`
+	Password string `env:"DIR_PASSWORD" secret:"true"`
+	APIKey   string `env:"DIR_API_KEY" file:"api-key"`
+	Port     int32  `env:"DIR_PORT" default:"80"`
+}
+
+func TestLoadFromDir(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "DIR_HOST"), []byte("db.internal\n"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "DIR_PASSWORD"), []byte("hunter2\n"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "api-key"), []byte(" abc123 "), 0o600))
+	t.Setenv("DIR_PASSWORD", "from-env")
+
+	var s dirStruct
+	assert.NoError(t, LoadFromDir(&s, dir))
+	assert.Equal(t, dirStruct{Host: "db.internal", Password: "from-env", APIKey: "abc123", Port: 80}, s)
+
+	report, err := LoadWithReport(&s, WithDir(dir))
+	assert.NoError(t, err)
+	assert.Equal(t, dirStruct{Host: "db.internal", Password: "from-env", APIKey: "abc123", Port: 80}, s)
+	assert.Equal(t, []Source{SourceFile, SourceEnv, SourceFile, SourceDefault}, []Source{report.Fields[0].Source,
+		report.Fields[1].Source, report.Fields[2].Source, report.Fields[3].Source})
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "DIR_PORT"), []byte("http"), 0o600))
+	assert.EqualError(t, LoadFromDir(&s, dir), "value for DIR_PORT could not be parsed as an integer")
+
+	assert.NoError(t, os.Remove(filepath.Join(dir, "DIR_PORT")))
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "DIR_PORT"), 0o700))
+	err = LoadFromDir(&s, dir)
+	assert.ErrorContains(t, err, "value for DIR_PORT could not be read from "+filepath.Join(dir, "DIR_PORT"))
+}
+
+func TestLoadFromDirCollections(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "DIR_TAGS"), []byte("a,b\n"), 0o600))
+	var s struct {
+		Tags   []string          `env:"DIR_TAGS"`
+		Labels map[string]string `env:"DIR_LABELS"`
+	}
+	assert.NoError(t, LoadFromDir(&s, dir))
+	assert.Equal(t, []string{"a", "b"}, s.Tags)
+	assert.Nil(t, s.Labels)
+}
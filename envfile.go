@@ -0,0 +1,80 @@
+package configstore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// LoadFromReader is the same as Load, but also reads values from KEY=VALUE lines in the reader, such as an embedded
+// .env file or a config blob delivered over a side channel. Env variables take precedence over the values read, and
+// fields set by neither fall back to their default. It accepts the same options as LoadContext
+func LoadFromReader(c interface{}, r io.Reader, opts ...LoadOption) error {
+	values, err := parseEnvFile(r)
+	if err != nil {
+		return err
+	}
+	return newSourcesLoader([]ValueSource{EnvSource(), MapSource(values)}, opts...).load(c)
+}
+
+// parseEnvFile parses the lines of a .env file. Blank lines and lines starting with # are skipped, as is an export
+// prefix, so shell env files can be read as they are. Values can be double quoted with Go escape sequences, as written
+// by GenerateEnvTemplate, or single quoted to be taken literally. Unquoted values end at a # preceded by whitespace
+func parseEnvFile(r io.Reader) (map[string]string, error) {
+	values := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d of env file is malformed, expected KEY=VALUE", lineNumber)
+		}
+		value, err := parseEnvFileValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("value for %s on line %d of env file has malformed quotes", key, lineNumber)
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("env file could not be read: %w", err)
+	}
+	return values, nil
+}
+
+// parseEnvFileValue unquotes a value from a .env file, or strips any trailing comment if it isn't quoted
+func parseEnvFileValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		quoted, err := strconv.QuotedPrefix(value)
+		if err != nil || !isEnvFileComment(value[len(quoted):]) {
+			return "", strconv.ErrSyntax
+		}
+		return strconv.Unquote(quoted)
+	case strings.HasPrefix(value, "'"):
+		end := strings.IndexByte(value[1:], '\'')
+		if end < 0 || !isEnvFileComment(value[end+2:]) {
+			return "", strconv.ErrSyntax
+		}
+		return value[1 : end+1], nil
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	if i := strings.Index(value, "\t#"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value), nil
+}
+
+// isEnvFileComment returns true if the rest of a line after a quoted value is empty or a comment
+func isEnvFileComment(rest string) bool {
+	rest = strings.TrimSpace(rest)
+	return rest == "" || strings.HasPrefix(rest, "#")
+}
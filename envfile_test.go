@@ -0,0 +1,69 @@
+package configstore
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type envFileStruct struct {
+	Host    string   `env:"ENVFILE_HOST" default:"localhost" desc:"Database host"`
+	Port    int32    `env:"ENVFILE_PORT" default:"5432"`
+	Motto   string   `env:"ENVFILE_MOTTO" default:"fast # and \"safe\""`
+	Tags    []string `env:"ENVFILE_TAGS"`
+	Comment string   `env:"ENVFILE_COMMENT"`
+}
+
+func TestLoadFromReader(t *testing.T) {
+	t.Setenv("ENVFILE_PORT", "6543")
+	file := `
+# database settings
+ENVFILE_HOST=db.internal # the primary
+export ENVFILE_PORT=7000
+ENVFILE_MOTTO = "say \"hi\" # not a comment"
+ENVFILE_TAGS='a,"b"'
+ENVFILE_COMMENT=#hashtag
+`
+	var s envFileStruct
+	assert.NoError(t, LoadFromReader(&s, strings.NewReader(file)))
+	assert.Equal(t, envFileStruct{
+		Host:    "db.internal",
+		Port:    6543,
+		Motto:   `say "hi" # not a comment`,
+		Tags:    []string{"a", "b"},
+		Comment: "#hashtag",
+	}, s)
+}
+
+func TestLoadFromReaderPrefersEnvFallbackNames(t *testing.T) {
+	t.Setenv("ENVFILE_OLD_HOST", "env.internal")
+	var s struct {
+		Host string `env:"ENVFILE_NEW_HOST,ENVFILE_OLD_HOST"`
+	}
+	assert.NoError(t, LoadFromReader(&s, strings.NewReader("ENVFILE_NEW_HOST=file.internal")))
+	assert.Equal(t, "env.internal", s.Host)
+}
+
+func TestLoadFromReaderReadsEnvTemplate(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	GenerateEnvTemplate(&buf, &envFileStruct{})
+	values, err := parseEnvFile(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, `fast # and "safe"`, values["ENVFILE_MOTTO"])
+	assert.Equal(t, "localhost", values["ENVFILE_HOST"])
+}
+
+func TestLoadFromReaderMalformed(t *testing.T) {
+	t.Parallel()
+	var s envFileStruct
+	err := LoadFromReader(&s, strings.NewReader("# settings\nENVFILE_HOST\n"))
+	assert.EqualError(t, err, "line 2 of env file is malformed, expected KEY=VALUE")
+
+	err = LoadFromReader(&s, strings.NewReader(`ENVFILE_HOST="db`))
+	assert.EqualError(t, err, "value for ENVFILE_HOST on line 1 of env file has malformed quotes")
+
+	err = LoadFromReader(&s, strings.NewReader(`ENVFILE_HOST='db' trailing`))
+	assert.EqualError(t, err, "value for ENVFILE_HOST on line 1 of env file has malformed quotes")
+}
@@ -0,0 +1,102 @@
+package configstore
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// LoadWithFlags loads config from command line flags, the execution environment and struct defaults, in that order of
+// precedence. Every field gets a flag named after its primary env variable, so STRING_VAL can be set with
+// --string-val. The args should not include the program name, so typically this is called with os.Args[1:]
+func LoadWithFlags(c interface{}, args []string) error {
+	if err := checkConfigPointer(c); err != nil {
+		return err
+	}
+	if err := checkDefinition(reflect.TypeOf(c).Elem()); err != nil {
+		return err
+	}
+	flagSet := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	flagValues, err := registerFlags(flagSet, c)
+	if err != nil {
+		return err
+	}
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	setFlags := map[string]string{}
+	flagSet.Visit(func(f *flag.Flag) {
+		setFlags[flagValues[f.Name].envVar] = f.Value.String()
+	})
+
+	lookupEnv := snapshotEnv()
+	return newLoader(func(key string) (string, bool) {
+		if value, ok := setFlags[key]; ok {
+			return value, true
+		}
+		return lookupEnv(key)
+	}).load(c)
+}
+
+// registerFlags adds a flag to the flag set for every field in the config, returning them keyed by flag name. Slices of
+// structs are skipped, as they're loaded from indexed env variables. Env variables that only differ by case or by - and
+// _ would share a flag, so they are reported as an error rather than letting the flag set panic
+func registerFlags(flagSet *flag.FlagSet, c interface{}) (map[string]*flagValue, error) {
+	flagValues := map[string]*flagValue{}
+	structType := reflect.ValueOf(c).Elem().Type()
+	for _, field := range configFields(structType) {
+		if isStructSlice(field.Type) {
+			continue
+		}
+		envVar := envVarName(field.Tag)
+		value := &flagValue{envVar: envVar, isBool: field.Type.Kind() == reflect.Bool}
+		usage := fmt.Sprintf("overrides the %s env variable", envVar)
+		if isEnvValueSecret(field.Tag) {
+			usage += " (secret, the value will be masked when printed)"
+		} else {
+			value.value, _ = staticDefault(field.Tag)
+		}
+
+		name := flagName(envVar)
+		if flagSet.Lookup(name) != nil {
+			owner := name
+			if existing, ok := flagValues[name]; ok {
+				owner = existing.envVar
+			}
+			return nil, fmt.Errorf("flag --%s for %s is already used by %s", name, envVar, owner)
+		}
+		flagSet.Var(value, name, usage)
+		flagValues[name] = value
+	}
+	return flagValues, nil
+}
+
+// flagName derives a flag name from an env variable, for example STRING_VAL becomes string-val
+func flagName(envVar string) string {
+	return strings.ReplaceAll(strings.ToLower(envVar), "_", "-")
+}
+
+// flagValue holds the raw string given for a flag, which is parsed along with the env variables when the config is
+// loaded
+type flagValue struct {
+	envVar string
+	value  string
+	isBool bool
+}
+
+func (f *flagValue) String() string {
+	return f.value
+}
+
+func (f *flagValue) Set(value string) error {
+	f.value = value
+	return nil
+}
+
+// IsBoolFlag allows bool fields to be set with a bare --flag
+func (f *flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
@@ -0,0 +1,67 @@
+package configstore
+
+import (
+	"bytes"
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type flagStruct struct {
+	StringValue string `env:"FLAG_STRING_VAL" default:"default_value"`
+	IntValue    int32  `env:"FLAG_INT_VAL" default:"1"`
+	BoolValue   bool   `env:"FLAG_BOOL_VAL" default:"false"`
+	SecretValue string `env:"FLAG_SECRET_VAL" secret:"true" default:"hunter2"`
+}
+
+func TestFlagName(t *testing.T) {
+	assert.Equal(t, "string-val", flagName("STRING_VAL"))
+}
+
+func TestLoadWithFlagsPrecedence(t *testing.T) {
+	t.Setenv("FLAG_STRING_VAL", "from_env")
+	t.Setenv("FLAG_INT_VAL", "2")
+
+	s := flagStruct{}
+	assert.NoError(t, LoadWithFlags(&s, []string{"--flag-string-val", "from_flag", "--flag-bool-val"}))
+	assert.Equal(t, flagStruct{
+		StringValue: "from_flag",
+		IntValue:    2,
+		BoolValue:   true,
+		SecretValue: "hunter2",
+	}, s)
+}
+
+func TestLoadWithFlagsErrors(t *testing.T) {
+	s := flagStruct{}
+	assert.Error(t, LoadWithFlags(&s, []string{"--unknown-flag"}))
+	assert.EqualError(t, LoadWithFlags(&s, []string{"--flag-int-val", "abc"}), "value for FLAG_INT_VAL could not be parsed as an integer")
+}
+
+func TestLoadWithFlagsDuplicates(t *testing.T) {
+	t.Parallel()
+	var duplicate struct {
+		First  string `env:"FLAG_DUPLICATE"`
+		Second string `env:"FLAG_DUPLICATE"`
+	}
+	assert.EqualError(t, LoadWithFlags(&duplicate, nil), "fields First and Second both read FLAG_DUPLICATE")
+
+	var clash struct {
+		Dashed     string `env:"FLAG-CLASH"`
+		Underscore string `env:"flag_clash"`
+	}
+	assert.EqualError(t, LoadWithFlags(&clash, nil), "flag --flag-clash for flag_clash is already used by FLAG-CLASH")
+}
+
+func TestRegisterFlagsSecretUsage(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	_, err := registerFlags(flagSet, &flagStruct{})
+	assert.NoError(t, err)
+
+	var usage bytes.Buffer
+	flagSet.SetOutput(&usage)
+	flagSet.PrintDefaults()
+	assert.Contains(t, usage.String(), "overrides the FLAG_SECRET_VAL env variable (secret, the value will be masked when printed)")
+	assert.NotContains(t, usage.String(), "hunter2")
+	assert.Contains(t, usage.String(), "(default default_value)")
+}
@@ -3,6 +3,7 @@ module github.com/levitatebio/configstore
 go 1.23.1
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/stretchr/testify v1.8.1
 	go.uber.org/zap v1.27.0
 )
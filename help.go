@@ -0,0 +1,83 @@
+package configstore
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PrintHelp writes a help screen describing every setting in the config: its env variables, type, default and the
+// description from its 'desc' struct tag. Required and secret settings are noted after the description, and the
+// defaults of secrets are shown as <secret>
+func PrintHelp(w io.Writer, c interface{}) {
+	writer := newTableWriter(w)
+	fmt.Fprint(writer, "ENV VAR\tTYPE\tDEFAULT\tDESCRIPTION\n")
+
+	for _, meta := range EnvVars(c) {
+		defaultValue := meta.Default
+		if meta.DefaultFn != "" {
+			defaultValue = meta.DefaultFn + "()"
+		}
+		if meta.Secret && defaultValue != "" {
+			defaultValue = "<secret>"
+		}
+
+		description := meta.Description
+		if notes := fieldNotes(meta); len(notes) > 0 {
+			description = strings.TrimSpace(fmt.Sprintf("%s (%s)", description, strings.Join(notes, ", ")))
+		}
+
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", strings.Join(meta.EnvVars, ", "), meta.Type, defaultValue, description)
+	}
+	writer.Flush()
+}
+
+// GenerateEnvTemplate writes a commented .env file for the config, which is useful as an example for developers. Each
+// setting is preceded by a comment with its description and type, and is set to its default. Secrets, required
+// settings and settings without a static default are left empty
+func GenerateEnvTemplate(w io.Writer, c interface{}) {
+	for i, meta := range EnvVars(c) {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		comment := meta.Type
+		if notes := fieldNotes(meta); len(notes) > 0 {
+			comment += ", " + strings.Join(notes, ", ")
+		}
+		if meta.DefaultFn != "" {
+			comment += ", defaults to " + meta.DefaultFn + "()"
+		}
+		if meta.Description != "" {
+			comment = fmt.Sprintf("%s (%s)", meta.Description, comment)
+		}
+		fmt.Fprintf(w, "# %s\n", comment)
+
+		value := meta.Default
+		if meta.Secret || meta.Required {
+			value = ""
+		}
+		fmt.Fprintf(w, "%s=%s\n", meta.EnvVars[0], quoteEnvValue(value))
+	}
+}
+
+// fieldNotes returns the notes about a field that are worth calling out in documentation
+func fieldNotes(meta FieldMeta) []string {
+	var notes []string
+	if meta.Required {
+		notes = append(notes, "required")
+	}
+	if meta.Secret {
+		notes = append(notes, "secret")
+	}
+	return notes
+}
+
+// quoteEnvValue double quotes a value for a .env file if it contains whitespace, quotes or comment characters
+func quoteEnvValue(value string) string {
+	if strings.ContainsAny(value, " \t\n\"'#") {
+		return strconv.Quote(value)
+	}
+	return value
+}
@@ -0,0 +1,48 @@
+package configstore
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type helpStruct struct {
+	Workers  int32  `env:"HELP_WORKERS" default:"4" desc:"Maximum concurrent workers"`
+	Host     string `env:"HELP_HOST,HELP_HOSTNAME" defaultFn:"hostname" desc:"Host to advertise"`
+	Password string `env:"HELP_PASSWORD" default:"hunter2" secret:"true" required:"true" desc:"Database password"`
+	APIKey   string `env:"HELP_API_KEY" secret:"true"`
+}
+
+func TestPrintHelp(t *testing.T) {
+	var out bytes.Buffer
+	PrintHelp(&out, &helpStruct{})
+	expected := "ENV VAR                    TYPE     DEFAULT      DESCRIPTION\n" +
+		"HELP_WORKERS               int32    4            Maximum concurrent workers\n" +
+		"HELP_HOST, HELP_HOSTNAME   string   hostname()   Host to advertise\n" +
+		"HELP_PASSWORD              string   <secret>     Database password (required, secret)\n" +
+		"HELP_API_KEY               string                (secret)\n"
+	assert.Equal(t, expected, out.String())
+}
+
+func TestGenerateEnvTemplate(t *testing.T) {
+	var out bytes.Buffer
+	GenerateEnvTemplate(&out, &helpStruct{})
+	expected := "# Maximum concurrent workers (int32)\n" +
+		"HELP_WORKERS=4\n" +
+		"\n" +
+		"# Host to advertise (string, defaults to hostname())\n" +
+		"HELP_HOST=\n" +
+		"\n" +
+		"# Database password (string, required, secret)\n" +
+		"HELP_PASSWORD=\n" +
+		"\n" +
+		"# string, secret\n" +
+		"HELP_API_KEY=\n"
+	assert.Equal(t, expected, out.String())
+}
+
+func TestQuoteEnvValue(t *testing.T) {
+	assert.Equal(t, "plain", quoteEnvValue("plain"))
+	assert.Equal(t, `"two words"`, quoteEnvValue("two words"))
+	assert.Equal(t, `"a#b"`, quoteEnvValue("a#b"))
+}
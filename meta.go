@@ -0,0 +1,46 @@
+package configstore
+
+import (
+	"reflect"
+)
+
+// FieldMeta describes a setting of a config as declared by its struct tags
+type FieldMeta struct {
+	// Field is the path of the field within the config, such as Primary.Host
+	Field string
+	// EnvVars are the env variables the field is read from, in order of precedence. Prefixes of nested structs are
+	// included
+	EnvVars []string
+	Type    string
+	// Default is the value of the 'default' struct tag. It isn't obscured for secrets
+	Default    string
+	HasDefault bool
+	// DefaultFn is the name of the function registered with RegisterDefaultFunc that provides the default, if any
+	DefaultFn   string
+	Required    bool
+	Secret      bool
+	Description string
+}
+
+// EnvVars describes every setting of the config in declaration order, including the fields of nested and embedded
+// structs. The config isn't changed or loaded, so this suits generating deployment manifests such as Helm values or
+// Terraform variables
+func EnvVars(c interface{}) []FieldMeta {
+	mustBeConfigPointer(c)
+	var metas []FieldMeta
+	for _, field := range configFields(reflect.ValueOf(c).Elem().Type()) {
+		defaultValue, hasDefault := staticDefault(field.Tag)
+		metas = append(metas, FieldMeta{
+			Field:       field.Name,
+			EnvVars:     append([]string(nil), envVarNames(field.Tag)...),
+			Type:        field.Type.String(),
+			Default:     defaultValue,
+			HasDefault:  hasDefault,
+			DefaultFn:   field.Tag.Get("defaultFn"),
+			Required:    isTagTrue(field.Tag, "required"),
+			Secret:      isEnvValueSecret(field.Tag),
+			Description: field.Tag.Get("desc"),
+		})
+	}
+	return metas
+}
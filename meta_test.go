@@ -0,0 +1,37 @@
+package configstore
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type metaDBConfig struct {
+	Host     string `env:"HOST" default:"localhost" desc:"Database host"`
+	Password string `env:"PASSWORD" secret:"true" required:"true"`
+}
+
+type metaCommon struct {
+	LogLevel string `env:"META_LOG_LEVEL,META_LEGACY_LOG_LEVEL" default:""`
+}
+
+type metaStruct struct {
+	metaCommon
+	Primary metaDBConfig `prefix:"META_PRIMARY_"`
+	Started string       `env:"META_STARTED" defaultFn:"now"`
+	Ignored string       `env:"-"`
+}
+
+func TestEnvVars(t *testing.T) {
+	t.Parallel()
+	c := metaStruct{Started: "preset"}
+	assert.Equal(t, []FieldMeta{
+		{Field: "LogLevel", EnvVars: []string{"META_LOG_LEVEL", "META_LEGACY_LOG_LEVEL"}, Type: "string", HasDefault: true},
+		{Field: "Primary.Host", EnvVars: []string{"META_PRIMARY_HOST"}, Type: "string", Default: "localhost",
+			HasDefault: true, Description: "Database host"},
+		{Field: "Primary.Password", EnvVars: []string{"META_PRIMARY_PASSWORD"}, Type: "string", Required: true, Secret: true},
+		{Field: "Started", EnvVars: []string{"META_STARTED"}, Type: "string", DefaultFn: "now"},
+	}, EnvVars(&c))
+	assert.Equal(t, metaStruct{Started: "preset"}, c)
+
+	assert.Panics(t, func() { EnvVars(c) })
+}
@@ -0,0 +1,47 @@
+package configstore
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	parsersMutex sync.RWMutex
+	parsers      = map[reflect.Type]func(raw string) (interface{}, error){}
+)
+
+// RegisterParser teaches the loader how to parse fields of the given type, such as uuid.UUID or decimal.Decimal. The
+// parser is called with the raw env value or default, and must return a value assignable to the type. Registered
+// parsers take precedence over the built in handling of a type. Fields of types without a TextMarshaler are printed
+// with fmt
+func RegisterParser(t reflect.Type, fn func(raw string) (interface{}, error)) {
+	parsersMutex.Lock()
+	defer parsersMutex.Unlock()
+	parsers[t] = fn
+}
+
+func getParser(t reflect.Type) (func(raw string) (interface{}, error), bool) {
+	parsersMutex.RLock()
+	defer parsersMutex.RUnlock()
+	fn, ok := parsers[t]
+	return fn, ok
+}
+
+// parseWithParser fills the field using a registered parser, checking that the value it returns can be assigned
+func (l *loader) parseWithParser(field reflect.StructField, fieldValue reflect.Value, parse func(raw string) (interface{}, error)) error {
+	valueString, err := l.getEnvValueString(field.Tag)
+	if err != nil {
+		return err
+	}
+	parsed, err := parse(valueString)
+	if err != nil {
+		return parseError(field.Tag, "parsed", err)
+	}
+	value := reflect.ValueOf(parsed)
+	if !value.IsValid() || !value.Type().AssignableTo(field.Type) {
+		return fmt.Errorf("parser for %s returned %T, which can't be assigned to %s", envVarName(field.Tag), parsed, field.Type)
+	}
+	fieldValue.Set(value)
+	return nil
+}
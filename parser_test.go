@@ -0,0 +1,55 @@
+package configstore
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+)
+
+type point struct {
+	X, Y int
+}
+
+type celsius float32
+
+type parserStruct struct {
+	Origin      point   `env:"PARSER_ORIGIN" default:"0:0"`
+	Temperature celsius `env:"PARSER_TEMPERATURE" default:"21.5"`
+}
+
+func init() {
+	RegisterParser(reflect.TypeOf(point{}), func(raw string) (interface{}, error) {
+		var p point
+		if _, err := fmt.Sscanf(raw, "%d:%d", &p.X, &p.Y); err != nil {
+			return nil, errors.New("expected x:y")
+		}
+		return p, nil
+	})
+	RegisterParser(reflect.TypeOf(celsius(0)), func(raw string) (interface{}, error) {
+		var c float32
+		_, err := fmt.Sscanf(raw, "%g", &c)
+		return c, err
+	})
+}
+
+func TestRegisterParser(t *testing.T) {
+	t.Parallel()
+	var s struct {
+		Origin point `env:"PARSER_ORIGIN" default:"0:0"`
+	}
+	assert.NoError(t, LoadFromMap(&s, map[string]string{"PARSER_ORIGIN": "3:4"}))
+	assert.Equal(t, point{3, 4}, s.Origin)
+	assert.Equal(t, "{3 4}", AsMap(&s)["PARSER_ORIGIN"])
+
+	assert.EqualError(t, LoadFromMap(&s, map[string]string{"PARSER_ORIGIN": "3"}),
+		"value for PARSER_ORIGIN could not be parsed: expected x:y")
+}
+
+func TestRegisterParserTypeCheck(t *testing.T) {
+	t.Parallel()
+	var s parserStruct
+	assert.EqualError(t, LoadFromMap(&s, nil),
+		"parser for PARSER_TEMPERATURE returned float32, which can't be assigned to configstore.celsius")
+}
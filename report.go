@@ -0,0 +1,63 @@
+package configstore
+
+import (
+	"reflect"
+)
+
+// Source describes where the value of a field came from
+type Source string
+
+const (
+	// SourceEnv means the value was read from an env variable
+	SourceEnv Source = "env"
+	// SourceDefault means none of the field's env variables were set, so its default was used
+	SourceDefault Source = "default"
+	// SourceFile means the value was read from a file
+	SourceFile Source = "file"
+	// SourceSecretResolver means the value was replaced by a SecretResolver
+	SourceSecretResolver Source = "secret-resolver"
+)
+
+// Report records where the value of every field in a config came from, which helps to answer why a setting has the
+// value it does
+type Report struct {
+	Fields []FieldReport
+}
+
+// FieldReport records the source of a single field's value. Secret values are obscured with the mask
+type FieldReport struct {
+	Field  string
+	EnvVar string
+	Source Source
+	Value  string
+}
+
+// LoadWithReport is the same as Load, but also reports the source of each field's value. It accepts the same options
+// as LoadContext
+func LoadWithReport(c interface{}, opts ...LoadOption) (Report, error) {
+	l := newLoader(snapshotEnv(), opts...)
+	if err := l.load(c); err != nil {
+		return Report{}, err
+	}
+	return l.report(c), nil
+}
+
+// report describes the fields of a config that has just been loaded
+func (l *loader) report(c interface{}) Report {
+	var report Report
+	structValue := reflect.ValueOf(c).Elem()
+	for _, field := range configFields(structValue.Type()) {
+		envVar, _, _ := l.lookupEnv(field.Tag)
+		value := encodeFieldValue(field, structValue.FieldByIndex(field.Index))
+		if isEnvValueSecret(field.Tag) {
+			value = maskSecret(field.Tag, value, getMask())
+		}
+		report.Fields = append(report.Fields, FieldReport{
+			Field:  field.Name,
+			EnvVar: envVar,
+			Source: l.sources[envVarName(field.Tag)],
+			Value:  value,
+		})
+	}
+	return report
+}
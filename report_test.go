@@ -0,0 +1,77 @@
+package configstore
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type reportStruct struct {
+	Host     string `env:"REPORT_HOST,REPORT_HOSTNAME" default:"localhost"`
+	Port     int32  `env:"REPORT_PORT" default:"80"`
+	Password string `env:"REPORT_PASSWORD" secret:"true"`
+}
+
+func TestLoadWithReport(t *testing.T) {
+	t.Setenv("REPORT_HOSTNAME", "example.com")
+	t.Setenv("REPORT_PASSWORD", "vault:password")
+	var s reportStruct
+	report, err := LoadWithReport(&s, WithSecretResolver(&vaultResolver{}))
+	assert.NoError(t, err)
+	assert.Equal(t, "resolved-password", s.Password)
+	assert.Equal(t, Report{Fields: []FieldReport{
+		{Field: "Host", EnvVar: "REPORT_HOSTNAME", Source: SourceEnv, Value: "example.com"},
+		{Field: "Port", EnvVar: "REPORT_PORT", Source: SourceDefault, Value: "80"},
+		{Field: "Password", EnvVar: "REPORT_PASSWORD", Source: SourceSecretResolver, Value: "********"},
+	}}, report)
+
+	t.Setenv("REPORT_PASSWORD", "hunter2")
+	report, err = LoadWithReport(&s, WithSecretResolver(&vaultResolver{}))
+	assert.NoError(t, err)
+	assert.Equal(t, SourceEnv, report.Fields[2].Source)
+
+	t.Setenv("REPORT_PORT", "http")
+	_, err = LoadWithReport(&s)
+	assert.Error(t, err)
+}
+
+func TestLoadWithReportCollections(t *testing.T) {
+	t.Setenv("REPORT_TAGS", "a,b")
+	var s struct {
+		Tags   []string          `env:"REPORT_TAGS"`
+		Hosts  []string          `env:"REPORT_HOSTS"`
+		Limits map[string]string `env:"REPORT_LIMITS"`
+		Key    []byte            `env:"REPORT_KEY"`
+	}
+	report, err := LoadWithReport(&s)
+	assert.NoError(t, err)
+	sources := []Source{}
+	for _, field := range report.Fields {
+		sources = append(sources, field.Source)
+	}
+	assert.Equal(t, []Source{SourceEnv, SourceDefault, SourceDefault, SourceDefault}, sources)
+}
+
+func TestPrintWithReport(t *testing.T) {
+	t.Setenv("REPORT_HOSTNAME", "example.com")
+	t.Setenv("REPORT_PASSWORD", "vault:password")
+	var s reportStruct
+	report, err := LoadWithReport(&s, WithSecretResolver(&vaultResolver{}))
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	Print(&s, WithWriter(&buf), WithReport(report))
+	assert.Equal(t, "OPTION     ENV VAR           SETTING       DEFAULT     SOURCE\n"+
+		"Host       REPORT_HOSTNAME   example.com   localhost   env\n"+
+		"Port       REPORT_PORT       80            80          default\n"+
+		"Password   REPORT_PASSWORD   ********                  secret-resolver\n", buf.String())
+
+	buf.Reset()
+	Print(&s, WithWriter(&buf), WithReport(report), WithFormat(FormatJSON), WithOverridesOnly(true))
+	assert.Contains(t, buf.String(), `"source": "env"`)
+	assert.NotContains(t, buf.String(), "resolved-password")
+
+	buf.Reset()
+	Print(&s, WithWriter(&buf))
+	assert.NotContains(t, buf.String(), "SOURCE")
+}
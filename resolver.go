@@ -0,0 +1,54 @@
+package configstore
+
+import (
+	"context"
+	"fmt"
+)
+
+// SecretResolver resolves the values of secret fields, for example by fetching them from a secret store such as Vault
+// when the value is a reference to one. It is called with the value taken from the environment or default, and should
+// return values it doesn't recognise unchanged. Resolvers that make network calls should respect the context
+type SecretResolver interface {
+	ResolveSecret(ctx context.Context, envVar string, value string) (string, error)
+}
+
+// SecretResolverFunc adapts a function to the SecretResolver interface
+type SecretResolverFunc func(ctx context.Context, envVar string, value string) (string, error)
+
+func (f SecretResolverFunc) ResolveSecret(ctx context.Context, envVar string, value string) (string, error) {
+	return f(ctx, envVar, value)
+}
+
+// WithSecretResolver passes the values of fields with a 'secret=true' struct tag through the resolver as they are
+// loaded
+func WithSecretResolver(resolver SecretResolver) LoadOption {
+	return func(l *loader) {
+		l.resolver = resolver
+	}
+}
+
+// LoadContext is the same as Load, but passes the context to any SecretResolver so that loading can be cancelled or
+// given a deadline. Loads that don't resolve any secrets never consult the context
+func LoadContext(ctx context.Context, c interface{}, opts ...LoadOption) error {
+	l := newLoader(snapshotEnv(), opts...)
+	l.ctx = ctx
+	return l.load(c)
+}
+
+// resolveSecret passes a secret value through the resolver, caching the result so each value is only resolved once per
+// load
+func (l *loader) resolveSecret(envVar string, value string) (string, error) {
+	key := envVar + "=" + value
+	if resolved, ok := l.resolved[key]; ok {
+		return resolved, nil
+	}
+	if err := l.ctx.Err(); err != nil {
+		return "", fmt.Errorf("secret for %s could not be resolved: %w", envVar, err)
+	}
+	resolved, err := l.resolver.ResolveSecret(l.ctx, envVar, value)
+	if err != nil {
+		return "", fmt.Errorf("secret for %s could not be resolved: %w", envVar, err)
+	}
+	l.resolved[key] = resolved
+	return resolved, nil
+}
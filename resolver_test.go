@@ -0,0 +1,91 @@
+package configstore
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type resolverStruct struct {
+	Password string `env:"RESOLVER_PASSWORD" secret:"true"`
+	Token    string `env:"RESOLVER_TOKEN" secret:"true" default:"vault:token"`
+	Host     string `env:"RESOLVER_HOST" default:"vault:host"`
+}
+
+// vaultResolver resolves values prefixed with vault: and counts the calls it receives
+type vaultResolver struct {
+	calls int
+}
+
+func (r *vaultResolver) ResolveSecret(ctx context.Context, envVar string, value string) (string, error) {
+	r.calls++
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(value, "vault:") {
+		return value, nil
+	}
+	return "resolved-" + strings.TrimPrefix(value, "vault:"), nil
+}
+
+func TestLoadContextResolvesSecrets(t *testing.T) {
+	t.Setenv("RESOLVER_PASSWORD", "vault:password")
+	resolver := &vaultResolver{}
+	var c resolverStruct
+	err := LoadContext(context.Background(), &c, WithSecretResolver(resolver))
+	assert.NoError(t, err)
+	assert.Equal(t, "resolved-password", c.Password)
+	assert.Equal(t, "resolved-token", c.Token)
+	assert.Equal(t, "vault:host", c.Host)
+	assert.Equal(t, 2, resolver.calls)
+}
+
+func TestLoadContextCancelled(t *testing.T) {
+	t.Setenv("RESOLVER_PASSWORD", "vault:password")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resolver := &vaultResolver{}
+	var c resolverStruct
+	err := LoadContext(ctx, &c, WithSecretResolver(resolver))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Contains(t, err.Error(), "secret for RESOLVER_PASSWORD could not be resolved")
+	assert.Equal(t, 0, resolver.calls)
+}
+
+func TestLoadContextWithoutResolver(t *testing.T) {
+	t.Setenv("RESOLVER_PASSWORD", "vault:password")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var c resolverStruct
+	assert.NoError(t, LoadContext(ctx, &c))
+	assert.Equal(t, "vault:password", c.Password)
+}
+
+func TestLoadContextResolverError(t *testing.T) {
+	t.Setenv("RESOLVER_PASSWORD", "vault:password")
+	resolver := SecretResolverFunc(func(ctx context.Context, envVar string, value string) (string, error) {
+		return "", errors.New("permission denied")
+	})
+	var c resolverStruct
+	err := LoadContext(context.Background(), &c, WithSecretResolver(resolver))
+	assert.EqualError(t, err, "2 config fields could not be loaded:\n"+
+		"  - secret for RESOLVER_PASSWORD could not be resolved: permission denied\n"+
+		"  - secret for RESOLVER_TOKEN could not be resolved: permission denied")
+}
+
+func TestLoadContextUsesEnvSnapshot(t *testing.T) {
+	t.Setenv("RESOLVER_PASSWORD", "password")
+	t.Setenv("RESOLVER_HOST", "db1")
+	resolver := SecretResolverFunc(func(ctx context.Context, envVar string, value string) (string, error) {
+		t.Setenv("RESOLVER_HOST", "db2")
+		return value, nil
+	})
+	var c resolverStruct
+	assert.NoError(t, LoadContext(context.Background(), &c, WithSecretResolver(resolver)))
+	assert.Equal(t, "db1", c.Host)
+
+	assert.NoError(t, LoadContext(context.Background(), &c))
+	assert.Equal(t, "db2", c.Host)
+}
@@ -0,0 +1,115 @@
+package configstore
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ValueSource provides the raw values of settings by env variable name, such as the environment or a file of settings.
+// It's called ValueSource rather than Source, which describes where a loaded value came from in a Report
+type ValueSource interface {
+	Lookup(key string) (string, bool)
+}
+
+// ValueSourceFunc adapts a lookup function such as os.LookupEnv to a ValueSource
+type ValueSourceFunc func(key string) (string, bool)
+
+func (f ValueSourceFunc) Lookup(key string) (string, bool) {
+	return f(key)
+}
+
+// LoadWithSources is the same as Load, but reads values from the sources rather than only the environment. Each field
+// is read from the first source that has one of its env variables, in the order the sources are given, and fields that
+// none of them have fall back to their default. All of a field's env variables, including fallback names, are tried in
+// one source before the next. For example, LoadWithSources(&config, EnvSource(), fileSource) lets env variables
+// override a file of settings
+func LoadWithSources(c interface{}, sources ...ValueSource) error {
+	return newSourcesLoader(sources).load(c)
+}
+
+// newSourcesLoader returns a loader reading from the sources in order. Each field is read from the first source that
+// has any of its env variables, so a fallback name set in an earlier source wins over the primary name in a later one
+func newSourcesLoader(sources []ValueSource, opts ...LoadOption) *loader {
+	lookups := sourceLookups(sources)
+	l := newLoader(chainLookups(lookups), opts...)
+	l.layers = lookups
+	return l
+}
+
+// sourceLookups returns the lookup functions of the sources. The environment is snapshotted once, so the whole load
+// sees a consistent view of it
+func sourceLookups(sources []ValueSource) []func(key string) (string, bool) {
+	lookups := make([]func(key string) (string, bool), len(sources))
+	for i, source := range sources {
+		lookups[i] = source.Lookup
+		if _, ok := source.(envSource); ok {
+			lookups[i] = snapshotEnv()
+		}
+	}
+	return lookups
+}
+
+// chainLookups returns a lookup function that consults the lookups in order
+func chainLookups(lookups []func(key string) (string, bool)) func(key string) (string, bool) {
+	return func(key string) (string, bool) {
+		for _, lookup := range lookups {
+			if value, ok := lookup(key); ok {
+				return value, true
+			}
+		}
+		return "", false
+	}
+}
+
+type envSource struct{}
+
+func (envSource) Lookup(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+// EnvSource reads values from the execution environment. LoadWithSources takes a snapshot of the environment for each
+// load, as Load does
+func EnvSource() ValueSource {
+	return envSource{}
+}
+
+// MapSource reads values from the map
+func MapSource(values map[string]string) ValueSource {
+	return ValueSourceFunc(mapLookup(values))
+}
+
+// EnvFileSource reads values from the KEY=VALUE lines of a .env file, as LoadFromReader does
+func EnvFileSource(r io.Reader) (ValueSource, error) {
+	values, err := parseEnvFile(r)
+	if err != nil {
+		return nil, err
+	}
+	return MapSource(values), nil
+}
+
+// TOMLSource reads values from the TOML file at the path, matching keys to env variables as LoadWithTOML does
+func TOMLSource(path string) (ValueSource, error) {
+	values, err := readTOMLFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return MapSource(values), nil
+}
+
+// DirSource reads values from files in the directory named after env variables, trimmed of surrounding whitespace, as
+// LoadFromDir does. Unlike LoadFromDir it ignores 'file' struct tags and treats files that can't be read as missing, so
+// use LoadFromDir if unreadable files should be reported
+func DirSource(dir string) ValueSource {
+	return ValueSourceFunc(func(key string) (string, bool) {
+		if key == "" || filepath.Base(key) != key {
+			return "", false
+		}
+		data, err := os.ReadFile(filepath.Join(dir, key))
+		if err != nil {
+			return "", false
+		}
+		return strings.TrimSpace(string(data)), true
+	})
+}
@@ -0,0 +1,82 @@
+package configstore
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type sourcesStruct struct {
+	Host    string `env:"SOURCES_HOST" default:"localhost"`
+	Port    int32  `env:"SOURCES_PORT" default:"80"`
+	Region  string `env:"SOURCES_REGION" default:"eu"`
+	Replica string `env:"SOURCES_REPLICA"`
+}
+
+func TestLoadWithSources(t *testing.T) {
+	t.Setenv("SOURCES_HOST", "from-env")
+	envFile, err := EnvFileSource(strings.NewReader("SOURCES_HOST=from-file\nSOURCES_PORT=8080\n"))
+	assert.NoError(t, err)
+	overrides := MapSource(map[string]string{"SOURCES_PORT": "9090"})
+
+	var s sourcesStruct
+	assert.NoError(t, LoadWithSources(&s, EnvSource(), envFile))
+	assert.Equal(t, sourcesStruct{Host: "from-env", Port: 8080, Region: "eu"}, s)
+
+	assert.NoError(t, LoadWithSources(&s, envFile, EnvSource()))
+	assert.Equal(t, sourcesStruct{Host: "from-file", Port: 8080, Region: "eu"}, s)
+
+	assert.NoError(t, LoadWithSources(&s, overrides, envFile))
+	assert.Equal(t, sourcesStruct{Host: "from-file", Port: 9090, Region: "eu"}, s)
+
+	assert.NoError(t, LoadWithSources(&s))
+	assert.Equal(t, sourcesStruct{Host: "localhost", Port: 80, Region: "eu"}, s)
+
+	lower := ValueSourceFunc(func(key string) (string, bool) {
+		return strings.ToLower(key), key == "SOURCES_REPLICA"
+	})
+	assert.NoError(t, LoadWithSources(&s, lower))
+	assert.Equal(t, "sources_replica", s.Replica)
+}
+
+func TestLoadWithSourcesFallbackNames(t *testing.T) {
+	t.Parallel()
+	type fallbackStruct struct {
+		Host string `env:"SOURCES_NEW_HOST,SOURCES_OLD_HOST"`
+	}
+	first := MapSource(map[string]string{"SOURCES_OLD_HOST": "first"})
+	second := MapSource(map[string]string{"SOURCES_NEW_HOST": "second"})
+
+	var s fallbackStruct
+	assert.NoError(t, LoadWithSources(&s, first, second))
+	assert.Equal(t, "first", s.Host)
+
+	assert.NoError(t, LoadWithSources(&s, second, first))
+	assert.Equal(t, "second", s.Host)
+}
+
+func TestLoadWithSourcesFiles(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "SOURCES_HOST"), []byte("db.internal\n"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "SOURCES_REGION"), []byte("us"), 0o600))
+	path := filepath.Join(dir, "config.toml")
+	assert.NoError(t, os.WriteFile(path, []byte("sources_port = 7000\nsources_region = \"ap\"\n"), 0o600))
+	toml, err := TOMLSource(path)
+	assert.NoError(t, err)
+
+	var s sourcesStruct
+	assert.NoError(t, LoadWithSources(&s, DirSource(dir), toml))
+	assert.Equal(t, sourcesStruct{Host: "db.internal", Port: 7000, Region: "us"}, s)
+
+	value, ok := DirSource(dir).Lookup("../" + filepath.Base(dir) + "/SOURCES_HOST")
+	assert.False(t, ok)
+	assert.Empty(t, value)
+
+	_, err = TOMLSource(filepath.Join(dir, "missing.toml"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = EnvFileSource(strings.NewReader("not a setting"))
+	assert.EqualError(t, err, "line 1 of env file is malformed, expected KEY=VALUE")
+}
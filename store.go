@@ -0,0 +1,61 @@
+package configstore
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Store holds a loaded config of type T that can be read and reloaded concurrently. Each load fills a fresh T which is
+// swapped in atomically, so readers always see a consistent snapshot without having to manage a mutex. T should be a
+// struct type, and the values returned by Load should be treated as immutable, as slices and maps are shared between
+// the snapshots handed out
+type Store[T any] struct {
+	value atomic.Value
+	opts  []LoadOption
+	// reloadMutex serialises Reload and Watch, so neither swaps in a config based on one the other has replaced
+	reloadMutex sync.Mutex
+	// loaded is the current config as it was loaded, before its defaults were applied. Watch starts from it so that
+	// defaults computed from the watched fields are worked out again. It's guarded by reloadMutex
+	loaded T
+}
+
+// NewStore loads a config of type T from the execution environment into a new Store. It accepts the same options as
+// LoadContext, which are used again whenever the config is reloaded
+func NewStore[T any](opts ...LoadOption) (*Store[T], error) {
+	s := &Store[T]{opts: opts}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Load returns the current snapshot of the config
+func (s *Store[T]) Load() T {
+	return s.value.Load().(T)
+}
+
+// Reload loads a fresh config from the execution environment and swaps it in. If loading fails the error is returned
+// and the current config is kept
+func (s *Store[T]) Reload() error {
+	s.reloadMutex.Lock()
+	defer s.reloadMutex.Unlock()
+	var c T
+	if err := checkConfigPointer(&c); err != nil {
+		return err
+	}
+	if err := newLoader(snapshotEnv(), s.opts...).fillCheckedConfig(&c); err != nil {
+		return err
+	}
+	loaded := c
+	if err := finishConfig(&c); err != nil {
+		return err
+	}
+	s.loaded = loaded
+	s.replace(c)
+	return nil
+}
+
+// replace atomically swaps in a new config
+func (s *Store[T]) replace(c T) {
+	s.value.Store(c)
+}
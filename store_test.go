@@ -0,0 +1,55 @@
+package configstore
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+type storeStruct struct {
+	Workers int32  `env:"STORE_WORKERS" default:"4"`
+	Name    string `env:"STORE_NAME" default:"default"`
+}
+
+func TestStore(t *testing.T) {
+	t.Setenv("STORE_NAME", "first")
+	store, err := NewStore[storeStruct]()
+	assert.NoError(t, err)
+	assert.Equal(t, storeStruct{Workers: 4, Name: "first"}, store.Load())
+
+	t.Setenv("STORE_NAME", "second")
+	assert.NoError(t, store.Reload())
+	assert.Equal(t, storeStruct{Workers: 4, Name: "second"}, store.Load())
+}
+
+func TestStoreReloadErrorKeepsConfig(t *testing.T) {
+	store, err := NewStore[storeStruct]()
+	assert.NoError(t, err)
+
+	t.Setenv("STORE_WORKERS", "many")
+	assert.Error(t, store.Reload())
+	assert.Equal(t, storeStruct{Workers: 4, Name: "default"}, store.Load())
+
+	_, err = NewStore[storeStruct]()
+	assert.Error(t, err)
+}
+
+func TestStoreConcurrentReads(t *testing.T) {
+	store, err := NewStore[storeStruct]()
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				assert.Equal(t, int32(4), store.Load().Workers)
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, store.Reload())
+	}
+	wg.Wait()
+}
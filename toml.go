@@ -0,0 +1,129 @@
+package configstore
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// LoadWithTOML is the same as Load, but also reads values from the TOML file at the path. Keys are matched to env
+// variables by joining the names of their tables with an underscore and upper-casing the result, so host in a [primary]
+// table is read as PRIMARY_HOST, which lines up with the prefix of a nested struct. Numbers, bools and dates are turned
+// back into text and arrays into comma separated lists, and are then parsed according to the field as usual. Env
+// variables take precedence over the file, and fields set by neither fall back to their default. It accepts the same
+// options as LoadContext
+func LoadWithTOML(c interface{}, path string, opts ...LoadOption) error {
+	values, err := readTOMLFile(path)
+	if err != nil {
+		return err
+	}
+	return newSourcesLoader([]ValueSource{EnvSource(), MapSource(values)}, opts...).load(c)
+}
+
+// readTOMLFile reads and parses the TOML file at the path
+func readTOMLFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("TOML file could not be read: %w", err)
+	}
+	values, err := parseTOML(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("TOML file %s could not be parsed: %w", path, err)
+	}
+	return values, nil
+}
+
+// parseTOML parses a TOML document into values keyed by the env variable each key maps to. Only tables, strings,
+// numbers, bools, dates and arrays of those make sense for flat settings, so arrays of tables and nested arrays, which
+// have no env equivalent, are rejected
+func parseTOML(data string) (map[string]string, error) {
+	var document map[string]interface{}
+	if _, err := toml.Decode(data, &document); err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	if err := flattenTOML(nil, document, values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// flattenTOML stores the values of a decoded table under the env variables of their keys, descending into nested tables
+func flattenTOML(path []string, table map[string]interface{}, values map[string]string) error {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		keyPath := append(append([]string{}, path...), key)
+		var value string
+		switch decoded := table[key].(type) {
+		case map[string]interface{}:
+			if err := flattenTOML(keyPath, decoded, values); err != nil {
+				return err
+			}
+			continue
+		case []map[string]interface{}:
+			return fmt.Errorf("%s is an array of tables, which is not supported", strings.Join(keyPath, "."))
+		case []interface{}:
+			elements := make([]string, len(decoded))
+			for i, element := range decoded {
+				text, ok := formatTOMLScalar(element)
+				if !ok {
+					return fmt.Errorf("%s can only hold strings, numbers, bools and dates", strings.Join(keyPath, "."))
+				}
+				elements[i] = quoteElement(text, `,"`)
+			}
+			value = strings.Join(elements, ",")
+		default:
+			text, ok := formatTOMLScalar(decoded)
+			if !ok {
+				return fmt.Errorf("%s has an unsupported value", strings.Join(keyPath, "."))
+			}
+			value = text
+		}
+		name := tomlEnvName(keyPath)
+		if _, ok := values[name]; ok {
+			return fmt.Errorf("%s is set more than once", name)
+		}
+		values[name] = value
+	}
+	return nil
+}
+
+// formatTOMLScalar returns the text of a decoded string, number, bool or date, as it would be written in an env
+// variable. Local dates and times are written without an offset
+func formatTOMLScalar(value interface{}) (string, bool) {
+	switch value := value.(type) {
+	case string:
+		return value, true
+	case int64:
+		return strconv.FormatInt(value, 10), true
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64), true
+	case bool:
+		return strconv.FormatBool(value), true
+	case time.Time:
+		switch value.Location().String() {
+		case "datetime-local":
+			return value.Format("2006-01-02T15:04:05.999999999"), true
+		case "date-local":
+			return value.Format(time.DateOnly), true
+		case "time-local":
+			return value.Format("15:04:05.999999999"), true
+		}
+		return value.Format(time.RFC3339Nano), true
+	}
+	return "", false
+}
+
+// tomlEnvName returns the env variable for the parts of a key, so primary.max-conns becomes PRIMARY_MAX_CONNS
+func tomlEnvName(path []string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.Join(path, "_"), "-", "_"))
+}
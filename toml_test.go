@@ -0,0 +1,143 @@
+package configstore
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type tomlDBConfig struct {
+	Host     string `env:"HOST" default:"localhost"`
+	MaxConns int32  `env:"MAX_CONNS" default:"10"`
+}
+
+type tomlStruct struct {
+	Name    string         `env:"TOML_NAME"`
+	Debug   bool           `env:"TOML_DEBUG" default:"false"`
+	Ratio   float64        `env:"TOML_RATIO"`
+	Timeout time.Duration  `env:"TOML_TIMEOUT" default:"5s"`
+	Tags    []string       `env:"TOML_TAGS"`
+	Ports   []int32        `env:"TOML_PORTS"`
+	Region  string         `env:"TOML_REGION" default:"eu"`
+	Primary tomlDBConfig   `prefix:"TOML_PRIMARY_"`
+	Limits  map[string]int `env:"TOML_LIMITS"`
+}
+
+func writeTOML(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	assert.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+	return path
+}
+
+func TestLoadWithTOML(t *testing.T) {
+	path := writeTOML(t, `
+# service settings
+toml_name = "api \"v2\"" # trailing comment
+TOML_DEBUG = true
+toml_ratio = 0.25
+toml_tags = [
+  "a",
+  "b,c", # quoted when flattened
+]
+toml_ports = [80, 0x1bb]
+toml_limits = "cpu=2,memory=512"
+
+[toml.primary]
+host = 'db.internal'
+max-conns = 1_000
+`)
+	t.Setenv("TOML_DEBUG", "false")
+
+	var s tomlStruct
+	assert.NoError(t, LoadWithTOML(&s, path))
+	assert.Equal(t, tomlStruct{
+		Name:    `api "v2"`,
+		Debug:   false,
+		Ratio:   0.25,
+		Timeout: 5 * time.Second,
+		Tags:    []string{"a", "b,c"},
+		Ports:   []int32{80, 443},
+		Region:  "eu",
+		Primary: tomlDBConfig{Host: "db.internal", MaxConns: 1000},
+		Limits:  map[string]int{"cpu": 2, "memory": 512},
+	}, s)
+}
+
+func TestLoadWithTOMLPrefersEnvFallbackNames(t *testing.T) {
+	t.Setenv("TOML_OLD_HOST", "env.internal")
+	var s struct {
+		Host string `env:"TOML_NEW_HOST,TOML_OLD_HOST"`
+	}
+	assert.NoError(t, LoadWithTOML(&s, writeTOML(t, `toml_new_host = "file.internal"`)))
+	assert.Equal(t, "env.internal", s.Host)
+}
+
+func TestParseTOML(t *testing.T) {
+	t.Parallel()
+	values, err := parseTOML(`
+title = """
+Multi-line \
+    basic"""
+quoted = """a""""
+path = 'C:\Users\config'
+raw = '''
+literal\n'''
+escaped = "tab\there \u00e9"
+created = 1979-05-27T07:32:00Z
+local = 1979-05-27 07:32:00
+day = 1979-05-27
+empty = []
+"quoted key" = -inf
+server = { host = "web", tls = { enabled = true } }
+[a . "b-c"]
+d = +1.5e3
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"TITLE":              "Multi-line basic",
+		"QUOTED":             `a"`,
+		"PATH":               `C:\Users\config`,
+		"RAW":                `literal\n`,
+		"ESCAPED":            "tab\there é",
+		"CREATED":            "1979-05-27T07:32:00Z",
+		"LOCAL":              "1979-05-27T07:32:00",
+		"DAY":                "1979-05-27",
+		"EMPTY":              "",
+		"QUOTED KEY":         "-Inf",
+		"SERVER_HOST":        "web",
+		"SERVER_TLS_ENABLED": "true",
+		"A_B_C_D":            "1500",
+	}, values)
+}
+
+func TestParseTOMLMalformed(t *testing.T) {
+	t.Parallel()
+	for input, expected := range map[string]string{
+		"name = bare":               `toml: line 1 (last key "name"): expected value but found "bare" instead`,
+		"\nname":                    "toml: line 2: unexpected EOF; expected key separator '='",
+		"name = \"bad \\q\"":        `toml: line 1 (last key "name"): invalid escape in string '\q'`,
+		"z = 1abc":                  "toml: line 1: expected a top-level item to end with a newline, comment, or EOF, but got 'a' instead",
+		"[a]\nb = 1\n[a]":           "toml: line 3: Key 'a' has already been defined.",
+		"a_b = 1\n[a]\nb = 2":       "A_B is set more than once",
+		"[[servers]]\nname = \"x\"": "servers is an array of tables, which is not supported",
+		"a = [[1], [2]]":            "a can only hold strings, numbers, bools and dates",
+	} {
+		_, err := parseTOML(input)
+		assert.EqualError(t, err, expected, input)
+	}
+}
+
+func TestLoadWithTOMLErrors(t *testing.T) {
+	var s tomlStruct
+	err := LoadWithTOML(&s, filepath.Join(t.TempDir(), "missing.toml"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	path := writeTOML(t, "toml_name = nope")
+	assert.EqualError(t, LoadWithTOML(&s, path),
+		"TOML file "+path+` could not be parsed: toml: line 1 (last key "toml_name"): expected value but found "nope" instead`)
+
+	path = writeTOML(t, "toml_ratio = \"lots\"")
+	assert.EqualError(t, LoadWithTOML(&s, path), "value for TOML_RATIO could not be parsed as a number")
+}
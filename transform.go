@@ -0,0 +1,52 @@
+package configstore
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	transformsMutex sync.RWMutex
+	transforms      = map[string]func(string) string{
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"trim":  strings.TrimSpace,
+	}
+)
+
+// RegisterTransform makes a function available to the 'transform' struct tag under the given name. Transforms
+// normalise the raw env value or default before it is parsed and validated, so transform:"upper" turns a region of
+// eu-west-1 into EU-WEST-1. The upper, lower and trim transforms are built in, and registering one of those names
+// replaces it
+func RegisterTransform(name string, fn func(string) string) {
+	transformsMutex.Lock()
+	defer transformsMutex.Unlock()
+	transforms[name] = fn
+}
+
+func getTransform(name string) (func(string) string, bool) {
+	transformsMutex.RLock()
+	defer transformsMutex.RUnlock()
+	fn, ok := transforms[name]
+	return fn, ok
+}
+
+// applyTransforms passes the value through each of the comma separated transforms in the field's 'transform' struct
+// tag, in order
+func applyTransforms(fieldTag reflect.StructTag, value string) (string, error) {
+	names := fieldTag.Get("transform")
+	if names == "" {
+		return value, nil
+	}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		fn, ok := getTransform(name)
+		if !ok {
+			return "", fmt.Errorf("transform %q for %s is not registered", name, envVarName(fieldTag))
+		}
+		value = fn(value)
+	}
+	return value, nil
+}
@@ -0,0 +1,41 @@
+package configstore
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type transformStruct struct {
+	Region string   `env:"TRANSFORM_REGION" transform:"trim,upper" oneof:"EU-WEST-1,US-EAST-1"`
+	Mode   string   `env:"TRANSFORM_MODE" default:"Fast" transform:"lower"`
+	Tags   []string `env:"TRANSFORM_TAGS" transform:"lower"`
+}
+
+func TestLoadTransforms(t *testing.T) {
+	t.Parallel()
+	var s transformStruct
+	err := LoadFromMap(&s, map[string]string{"TRANSFORM_REGION": " eu-west-1 ", "TRANSFORM_TAGS": "A,b"})
+	assert.NoError(t, err)
+	assert.Equal(t, transformStruct{Region: "EU-WEST-1", Mode: "fast", Tags: []string{"a", "b"}}, s)
+
+	err = LoadFromMap(&s, map[string]string{"TRANSFORM_REGION": "ap-south-1"})
+	assert.ErrorContains(t, err, "AP-SOUTH-1")
+}
+
+func TestRegisterTransform(t *testing.T) {
+	t.Parallel()
+	RegisterTransform("dashes", func(value string) string {
+		return strings.ReplaceAll(value, "_", "-")
+	})
+	var s struct {
+		Host string `env:"TRANSFORM_HOST" transform:"dashes,lower"`
+	}
+	assert.NoError(t, LoadFromMap(&s, map[string]string{"TRANSFORM_HOST": "DB_PRIMARY"}))
+	assert.Equal(t, "db-primary", s.Host)
+
+	var missing struct {
+		Host string `env:"TRANSFORM_HOST" transform:"slugify"`
+	}
+	assert.EqualError(t, LoadFromMap(&missing, nil), `transform "slugify" for TRANSFORM_HOST is not registered`)
+}
@@ -0,0 +1,97 @@
+package configstore
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	variantsMutex sync.RWMutex
+	variants      = map[string]map[string]func() interface{}{}
+)
+
+// RegisterVariant registers a concrete config type for interface fields whose env variable is envVar, chosen by its
+// value. Registering "s3" for STORAGE_BACKEND means that STORAGE_BACKEND=s3 creates a config with the factory, fills
+// it from the environment and assigns it to the field. The factory must return a pointer to a config struct that
+// implements the field's interface
+func RegisterVariant(envVar string, discriminator string, factory func() interface{}) {
+	variantsMutex.Lock()
+	defer variantsMutex.Unlock()
+	if variants[envVar] == nil {
+		variants[envVar] = map[string]func() interface{}{}
+	}
+	variants[envVar][discriminator] = factory
+}
+
+// getVariants returns the factories registered for the env variable, keyed by discriminator
+func getVariants(envVar string) map[string]func() interface{} {
+	variantsMutex.RLock()
+	defer variantsMutex.RUnlock()
+	return variants[envVar]
+}
+
+// fillVariant fills an interface field with the variant named by its env variable, leaving it nil if the variable
+// isn't set and has no default
+func (l *loader) fillVariant(field reflect.StructField, fieldValue reflect.Value) error {
+	discriminator, err := l.getEnvValueString(field.Tag)
+	if err != nil {
+		return err
+	}
+	if discriminator == "" {
+		fieldValue.Set(reflect.Zero(field.Type))
+		return nil
+	}
+	registered := getVariants(envVarName(field.Tag))
+	factory, ok := registered[discriminator]
+	if !ok {
+		return fmt.Errorf("value %q for %s is not a registered variant, it must be one of %s", discriminator,
+			envVarName(field.Tag), strings.Join(variantNames(registered), ", "))
+	}
+	variant := factory()
+	if err := checkConfigPointer(variant); err != nil {
+		return fmt.Errorf("variant %q for %s is not supported: %w", discriminator, envVarName(field.Tag), err)
+	}
+	if err := l.loadConfig(variant); err != nil {
+		return err
+	}
+	return assignValue(field, fieldValue, reflect.ValueOf(variant))
+}
+
+// variantName returns the discriminator of the variant held by an interface field, or an empty string if it is nil or
+// of a type that wasn't registered
+func variantName(field reflect.StructField, value reflect.Value) string {
+	if value.IsNil() {
+		return ""
+	}
+	registered := getVariants(envVarName(field.Tag))
+	for _, name := range variantNames(registered) {
+		if reflect.TypeOf(registered[name]()) == value.Elem().Type() {
+			return name
+		}
+	}
+	return ""
+}
+
+// variantNames returns the sorted discriminators of the registered variants
+func variantNames(registered map[string]func() interface{}) []string {
+	names := make([]string, 0, len(registered))
+	for name := range registered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// flattenFieldErrors appends the errors to the list, expanding a *FieldErrors into the errors it holds so that the
+// fields of variants are reported alongside the rest
+func flattenFieldErrors(errs []error, err error) []error {
+	var fieldErrs *FieldErrors
+	if errors.As(err, &fieldErrs) {
+		return append(errs, fieldErrs.Errs...)
+	}
+	return append(errs, err)
+}
@@ -0,0 +1,134 @@
+package configstore
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type storageConfig interface {
+	Location() string
+}
+
+type s3Storage struct {
+	Bucket    string `env:"VARIANT_S3_BUCKET"`
+	SecretKey string `env:"VARIANT_S3_SECRET_KEY" secret:"true"`
+}
+
+func (s *s3Storage) Location() string { return "s3://" + s.Bucket }
+
+type localStorage struct {
+	Path string `env:"VARIANT_LOCAL_PATH" default:"/var/data"`
+	Port int32  `env:"VARIANT_LOCAL_PORT" default:"0"`
+}
+
+func (s *localStorage) Location() string { return s.Path }
+
+type queueStorage struct {
+	URL     string `env:"VARIANT_QUEUE_URL" default:""`
+	Retries int32  `env:"VARIANT_QUEUE_RETRIES" default:"0"`
+}
+
+func (s *queueStorage) Location() string { return s.URL }
+
+func (s *queueStorage) ApplyDefaults() {
+	if s.Retries == 0 {
+		s.Retries = 3
+	}
+}
+
+func (s *queueStorage) Validate() error {
+	if s.URL == "" {
+		return errors.New("a queue needs a URL")
+	}
+	return nil
+}
+
+type clashingStorage struct {
+	First  string `env:"VARIANT_CLASH"`
+	Second string `env:"VARIANT_CLASH"`
+}
+
+func (s *clashingStorage) Location() string { return s.First }
+
+type queueStruct struct {
+	Queue storageConfig `env:"VARIANT_QUEUE_BACKEND"`
+}
+
+type variantStruct struct {
+	Name    string        `env:"VARIANT_NAME" default:"app"`
+	Storage storageConfig `env:"VARIANT_STORAGE_BACKEND" default:"local"`
+	Cache   storageConfig `env:"VARIANT_CACHE_BACKEND"`
+}
+
+func init() {
+	RegisterVariant("VARIANT_STORAGE_BACKEND", "s3", func() interface{} { return &s3Storage{} })
+	RegisterVariant("VARIANT_STORAGE_BACKEND", "local", func() interface{} { return &localStorage{} })
+	RegisterVariant("VARIANT_CACHE_BACKEND", "local", func() interface{} { return &localStorage{} })
+	RegisterVariant("VARIANT_CACHE_BACKEND", "broken", func() interface{} { return localStorage{} })
+	RegisterVariant("VARIANT_QUEUE_BACKEND", "queue", func() interface{} { return &queueStorage{} })
+	RegisterVariant("VARIANT_QUEUE_BACKEND", "clash", func() interface{} { return &clashingStorage{} })
+}
+
+func TestLoadVariant(t *testing.T) {
+	t.Parallel()
+	var s variantStruct
+	assert.NoError(t, LoadFromMap(&s, nil))
+	assert.Equal(t, &localStorage{Path: "/var/data"}, s.Storage)
+	assert.Nil(t, s.Cache)
+
+	values := map[string]string{
+		"VARIANT_STORAGE_BACKEND": "s3",
+		"VARIANT_S3_BUCKET":       "assets",
+		"VARIANT_S3_SECRET_KEY":   "hunter2",
+	}
+	assert.NoError(t, LoadFromMap(&s, values))
+	assert.Equal(t, "s3://assets", s.Storage.Location())
+
+	var buf bytes.Buffer
+	Print(&s, WithWriter(&buf))
+	assert.Equal(t, "OPTION    ENV VAR                   SETTING   DEFAULT\n"+
+		"Name      VARIANT_NAME              app       app\n"+
+		"Storage   VARIANT_STORAGE_BACKEND   s3        local\n"+
+		"Cache     VARIANT_CACHE_BACKEND               \n", buf.String())
+}
+
+func TestLoadVariantDefaultsAndValidation(t *testing.T) {
+	t.Parallel()
+	var s queueStruct
+	assert.NoError(t, LoadFromMap(&s, map[string]string{"VARIANT_QUEUE_BACKEND": "queue", "VARIANT_QUEUE_URL": "amqp://q"}))
+	assert.Equal(t, &queueStorage{URL: "amqp://q", Retries: 3}, s.Queue)
+
+	err := LoadFromMap(&s, map[string]string{"VARIANT_QUEUE_BACKEND": "queue"})
+	assert.EqualError(t, err, "config validation failed: a queue needs a URL")
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+
+	err = LoadFromMap(&s, map[string]string{"VARIANT_QUEUE_BACKEND": "clash"})
+	assert.EqualError(t, err, "fields First and Second both read VARIANT_CLASH")
+}
+
+func TestLoadStrictVariant(t *testing.T) {
+	t.Setenv("VARIANT_STORAGE_BACKEND", "s3")
+	t.Setenv("VARIANT_S3_BUCKET", "assets")
+	var s variantStruct
+	assert.NoError(t, LoadStrict(&s, "VARIANT_"))
+	assert.Equal(t, "s3://assets", s.Storage.Location())
+
+	t.Setenv("VARIANT_S3_BUKET", "typo")
+	assert.EqualError(t, LoadStrict(&s, "VARIANT_"), "unknown env variables with prefix VARIANT_: VARIANT_S3_BUKET")
+}
+
+func TestLoadVariantErrors(t *testing.T) {
+	t.Parallel()
+	var s variantStruct
+	err := LoadFromMap(&s, map[string]string{"VARIANT_STORAGE_BACKEND": "gcs", "VARIANT_CACHE_BACKEND": "broken"})
+	assert.EqualError(t, err, "2 config fields could not be loaded:\n"+
+		`  - value "gcs" for VARIANT_STORAGE_BACKEND is not a registered variant, it must be one of local, s3`+"\n"+
+		`  - variant "broken" for VARIANT_CACHE_BACKEND is not supported: configstore: expected pointer to struct, got configstore.localStorage`)
+
+	err = LoadFromMap(&s, map[string]string{"VARIANT_STORAGE_BACKEND": "s3", "VARIANT_CACHE_BACKEND": "local",
+		"VARIANT_LOCAL_PORT": "http"})
+	assert.EqualError(t, err, "value for VARIANT_LOCAL_PORT could not be parsed as an integer")
+}
@@ -0,0 +1,152 @@
+package configstore
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// defaultWatchInterval is how often Watch re-resolves secrets unless WatchInterval is given
+const defaultWatchInterval = time.Minute
+
+type watchOptions struct {
+	interval time.Duration
+	envVars  []string
+}
+
+// WatchOption configures Store.Watch
+type WatchOption func(*watchOptions)
+
+// WatchInterval sets how often Watch re-resolves the watched fields, which is every minute by default
+func WatchInterval(interval time.Duration) WatchOption {
+	return func(options *watchOptions) {
+		options.interval = interval
+	}
+}
+
+// WatchFields limits Watch to the fields with the given primary env variables, rather than every field tagged
+// 'secret=true'. Fields that aren't secret can be watched too
+func WatchFields(envVars ...string) WatchOption {
+	return func(options *watchOptions) {
+		options.envVars = envVars
+	}
+}
+
+// Watch keeps secrets fresh while the service runs, for example when credentials are rotated in a secret store. At
+// every interval the watched fields are loaded again, passing secrets through the store's SecretResolver, and if any of
+// them changed the updated config is validated, swapped in and passed to onChange. Defaults are applied afresh, so ones
+// computed from a watched field follow its changes. Errors are sent on the returned
+// channel and leave the current config in place. The channel must be drained, as watching pauses until each error is
+// received, and it's closed once the context is done. An error is returned straight away if the interval isn't
+// positive or a watched env variable doesn't belong to any field
+func (s *Store[T]) Watch(ctx context.Context, onChange func(T), opts ...WatchOption) (<-chan error, error) {
+	options := watchOptions{interval: defaultWatchInterval}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.interval <= 0 {
+		return nil, fmt.Errorf("watch interval must be positive, got %s", options.interval)
+	}
+	fields, err := watchedFields(reflect.TypeOf(s.Load()), options.envVars)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make(chan error)
+	go func() {
+		defer close(errs)
+		ticker := time.NewTicker(options.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			next, changed, err := s.refresh(ctx, fields)
+			if changed && onChange != nil {
+				onChange(next)
+			}
+			if err != nil {
+				select {
+				case errs <- err:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return errs, nil
+}
+
+// watchedFields returns the fields with the given primary env variables, or every secret field if none are given
+func watchedFields(structType reflect.Type, envVars []string) ([]reflect.StructField, error) {
+	var fields []reflect.StructField
+	if len(envVars) == 0 {
+		for _, field := range configFields(structType) {
+			if isEnvValueSecret(field.Tag) {
+				fields = append(fields, field)
+			}
+		}
+		return fields, nil
+	}
+	for _, envVar := range envVars {
+		found := false
+		for _, field := range configFields(structType) {
+			if envVarName(field.Tag) == envVar {
+				fields = append(fields, field)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s can't be watched, no field of %s is loaded from it", envVar, structType)
+		}
+	}
+	return fields, nil
+}
+
+// refresh loads the fields into a copy of the current config as it was loaded and swaps it in if any of them changed,
+// returning the new config. Starting from the config before its defaults were applied means defaults computed from the
+// watched fields are worked out again. Defaults are applied before the fields are compared and validated, as they are
+// by Load. Nothing is swapped in if any field fails to load or the updated config fails validation
+func (s *Store[T]) refresh(ctx context.Context, fields []reflect.StructField) (T, bool, error) {
+	s.reloadMutex.Lock()
+	defer s.reloadMutex.Unlock()
+	current := s.Load()
+	loaded := s.loaded
+	l := newLoader(snapshotEnv(), s.opts...)
+	l.ctx = ctx
+	loadedValue := reflect.ValueOf(&loaded).Elem()
+	var errs []error
+	for _, field := range fields {
+		if err := l.loadField(field, loadedValue.FieldByIndex(field.Index)); err != nil {
+			errs = flattenFieldErrors(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return current, false, &FieldErrors{Errs: errs}
+	}
+	next := loaded
+	currentValue, nextValue := reflect.ValueOf(current), reflect.ValueOf(&next).Elem()
+	if defaulter, ok := interface{}(&next).(Defaulter); ok {
+		defaulter.ApplyDefaults()
+	}
+	changed := false
+	for _, field := range fields {
+		changed = changed || !reflect.DeepEqual(nextValue.FieldByIndex(field.Index).Interface(),
+			currentValue.FieldByIndex(field.Index).Interface())
+	}
+	if !changed {
+		return current, false, nil
+	}
+	if validator, ok := interface{}(&next).(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return current, false, &ValidationError{Err: err}
+		}
+	}
+	s.loaded = loaded
+	s.replace(next)
+	return next, true, nil
+}
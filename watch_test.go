@@ -0,0 +1,163 @@
+package configstore
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type watchStruct struct {
+	Token    string `env:"WATCH_TOKEN" secret:"true" default:"vault:token"`
+	Password string `env:"WATCH_PASSWORD" secret:"true" default:"vault:password"`
+	Host     string `env:"WATCH_HOST" default:"vault:host"`
+}
+
+// rotatingResolver resolves vault: references to the current version of a secret, or fails if err is set
+type rotatingResolver struct {
+	mu      sync.Mutex
+	version int
+	err     error
+}
+
+func (r *rotatingResolver) rotate(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version++
+	r.err = err
+}
+
+func (r *rotatingResolver) ResolveSecret(ctx context.Context, envVar string, value string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return "", r.err
+	}
+	return value + "-v" + string(rune('0'+r.version)), nil
+}
+
+func TestStoreWatch(t *testing.T) {
+	resolver := &rotatingResolver{}
+	store, err := NewStore[watchStruct](WithSecretResolver(resolver))
+	assert.NoError(t, err)
+	assert.Equal(t, watchStruct{Token: "vault:token-v0", Password: "vault:password-v0", Host: "vault:host"}, store.Load())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan watchStruct, 10)
+	errs, err := store.Watch(ctx, func(c watchStruct) { changes <- c }, WatchInterval(5*time.Millisecond))
+	assert.NoError(t, err)
+
+	resolver.rotate(nil)
+	expected := watchStruct{Token: "vault:token-v1", Password: "vault:password-v1", Host: "vault:host"}
+	select {
+	case c := <-changes:
+		assert.Equal(t, expected, c)
+	case <-time.After(time.Second):
+		t.Fatal("the rotated secrets were not picked up")
+	}
+	assert.Equal(t, expected, store.Load())
+
+	resolver.rotate(errors.New("permission denied"))
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "2 config fields could not be loaded:\n"+
+			"  - secret for WATCH_TOKEN could not be resolved: permission denied\n"+
+			"  - secret for WATCH_PASSWORD could not be resolved: permission denied")
+	case <-time.After(time.Second):
+		t.Fatal("the resolver error was not reported")
+	}
+	assert.Equal(t, expected, store.Load())
+
+	cancel()
+	for range errs {
+	}
+	assert.Len(t, changes, 0)
+}
+
+type defaultedWatchStruct struct {
+	Host        string `env:"WATCH_DEFAULTED_HOST"`
+	Port        int32  `env:"WATCH_DEFAULTED_PORT" default:"0"`
+	MetricsPort int32  `env:"WATCH_DEFAULTED_METRICS_PORT" default:"0"`
+}
+
+func (s *defaultedWatchStruct) ApplyDefaults() {
+	if s.Port == 0 {
+		s.Port = 80
+	}
+	if s.MetricsPort == 0 {
+		s.MetricsPort = s.Port + 1
+	}
+}
+
+func (s *defaultedWatchStruct) Validate() error {
+	if s.Port == 0 {
+		return errors.New("a port is needed")
+	}
+	return nil
+}
+
+func TestStoreRefreshAppliesDefaults(t *testing.T) {
+	t.Setenv("WATCH_DEFAULTED_HOST", "a.example.com")
+	store, err := NewStore[defaultedWatchStruct]()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultedWatchStruct{Host: "a.example.com", Port: 80, MetricsPort: 81}, store.Load())
+
+	fields := configFields(reflect.TypeOf(defaultedWatchStruct{}))
+	_, changed, err := store.refresh(context.Background(), fields)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	t.Setenv("WATCH_DEFAULTED_HOST", "b.example.com")
+	next, changed, err := store.refresh(context.Background(), fields)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, defaultedWatchStruct{Host: "b.example.com", Port: 80, MetricsPort: 81}, next)
+	assert.Equal(t, next, store.Load())
+
+	t.Setenv("WATCH_DEFAULTED_PORT", "8080")
+	next, changed, err = store.refresh(context.Background(), fields)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, defaultedWatchStruct{Host: "b.example.com", Port: 8080, MetricsPort: 8081}, next)
+
+	t.Setenv("WATCH_DEFAULTED_PORT", "9090")
+	next, changed, err = store.refresh(context.Background(), fields)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, defaultedWatchStruct{Host: "b.example.com", Port: 9090, MetricsPort: 9091}, next)
+}
+
+func TestStoreWatchFields(t *testing.T) {
+	resolver := &rotatingResolver{}
+	store, err := NewStore[watchStruct](WithSecretResolver(resolver))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan watchStruct, 10)
+	_, err = store.Watch(ctx, func(c watchStruct) { changes <- c }, WatchInterval(5*time.Millisecond),
+		WatchFields("WATCH_PASSWORD"))
+	assert.NoError(t, err)
+
+	resolver.rotate(nil)
+	select {
+	case c := <-changes:
+		assert.Equal(t, watchStruct{Token: "vault:token-v0", Password: "vault:password-v1", Host: "vault:host"}, c)
+	case <-time.After(time.Second):
+		t.Fatal("the rotated secret was not picked up")
+	}
+}
+
+func TestStoreWatchOptionErrors(t *testing.T) {
+	store, err := NewStore[watchStruct]()
+	assert.NoError(t, err)
+
+	_, err = store.Watch(context.Background(), nil, WatchInterval(0))
+	assert.EqualError(t, err, "watch interval must be positive, got 0s")
+
+	_, err = store.Watch(context.Background(), nil, WatchFields("WATCH_MISSING"))
+	assert.EqualError(t, err, "WATCH_MISSING can't be watched, no field of configstore.watchStruct is loaded from it")
+}